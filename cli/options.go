@@ -18,10 +18,13 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -80,6 +83,8 @@ type ProjectOptions struct {
 	EnvFiles []string
 
 	loadOptions []func(*loader.Options)
+	// loadOptionsOverrides are set by WithLoadOptions and applied after loadOptions
+	loadOptionsOverrides []func(*loader.Options)
 
 	// Callbacks to retrieve metadata information during parse defined before
 	// creating the project
@@ -104,17 +109,38 @@ func NewProjectOptions(configs []string, opts ...ProjectOptionsFn) (*ProjectOpti
 	return options, nil
 }
 
-// WithName defines ProjectOptions' name
+// Clone returns a copy of ProjectOptions which can be modified without affecting the original one.
+// Slices and the Environment map are copied. Function-valued fields (loader options and listeners) are
+// copied as slices but the functions themselves are shared, so closures capturing mutable state will
+// observe the same state from both instances.
+func (o *ProjectOptions) Clone() *ProjectOptions {
+	return &ProjectOptions{
+		ctx:                  o.ctx,
+		Name:                 o.Name,
+		WorkingDir:           o.WorkingDir,
+		ConfigPaths:          slices.Clone(o.ConfigPaths),
+		Environment:          maps.Clone(o.Environment),
+		EnvFiles:             slices.Clone(o.EnvFiles),
+		loadOptions:          slices.Clone(o.loadOptions),
+		loadOptionsOverrides: slices.Clone(o.loadOptionsOverrides),
+		Listeners:            slices.Clone(o.Listeners),
+	}
+}
+
+// WithName defines ProjectOptions' name, which takes precedence over COMPOSE_PROJECT_NAME and the name derived from
+// the working directory. The name is normalized like a derived name would be, being lowercased and stripped of
+// unsupported characters
 func WithName(name string) ProjectOptionsFn {
 	return func(o *ProjectOptions) error {
 		// a project (once loaded) cannot have an empty name
 		// however, on the options object, the name is optional: if unset,
 		// a name will be inferred by the loader, so it's legal to set the
 		// name to an empty string here
-		if name != loader.NormalizeProjectName(name) {
+		normalized := loader.NormalizeProjectName(name)
+		if name != "" && normalized == "" {
 			return loader.InvalidProjectNameErr(name)
 		}
-		o.Name = name
+		o.Name = normalized
 		return nil
 	}
 }
@@ -152,6 +178,71 @@ func WithConfigFileEnv(o *ProjectOptions) error {
 	return nil
 }
 
+// WithConfigPathsFromGlob sets compose config file paths to the files matching a glob pattern, relative to the
+// working directory. Files are sorted so that less specific names come first, by number of `.` separated
+// segments in the file name then lexically, e.g. `compose.yaml` comes before `compose.prod.yaml`, so the first
+// file is the base compose file and the others are applied as overrides.
+// Files whose name matches one of the exclude patterns, typically `.*` for hidden files or `*~` for backups,
+// are ignored.
+func WithConfigPathsFromGlob(pattern string, exclude ...string) ProjectOptionsFn {
+	return func(o *ProjectOptions) error {
+		if len(o.ConfigPaths) > 0 {
+			return nil
+		}
+		pwd, err := o.GetWorkingDir()
+		if err != nil {
+			return err
+		}
+		glob := pattern
+		if !filepath.IsAbs(glob) {
+			glob = filepath.Join(pwd, glob)
+		}
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return err
+		}
+		var paths []string
+		for _, match := range matches {
+			excluded, err := matchesAny(filepath.Base(match), exclude)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				continue
+			}
+			if fi, err := os.Stat(match); err != nil || fi.IsDir() {
+				continue
+			}
+			paths = append(paths, match)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no compose file matches %s: %w", pattern, errdefs.ErrNotFound)
+		}
+		slices.SortFunc(paths, func(a, b string) int {
+			sa, sb := strings.Count(filepath.Base(a), "."), strings.Count(filepath.Base(b), ".")
+			if sa != sb {
+				return sa - sb
+			}
+			return strings.Compare(a, b)
+		})
+		o.ConfigPaths = paths
+		return nil
+	}
+}
+
+func matchesAny(name string, patterns []string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // WithDefaultConfigPath searches for default config files from working directory
 func WithDefaultConfigPath(o *ProjectOptions) error {
 	if len(o.ConfigPaths) > 0 {
@@ -200,28 +291,51 @@ func WithEnv(env []string) ProjectOptionsFn {
 	}
 }
 
+// WithDiscardEnvFiles discards the `env_file` section once env files have been successfully resolved into the
+// `environment` section, so that the resulting model is self-contained and doesn't expose local paths
+func WithDiscardEnvFiles(o *ProjectOptions) error {
+	o.loadOptions = append(o.loadOptions, loader.WithDiscardEnvFiles)
+	return nil
+}
+
 // WithDiscardEnvFile sets discards the `env_file` section after resolving to
 // the `environment` section
+//
+// Deprecated: use WithDiscardEnvFiles instead.
 func WithDiscardEnvFile(o *ProjectOptions) error {
-	o.loadOptions = append(o.loadOptions, loader.WithDiscardEnvFiles)
-	return nil
+	return WithDiscardEnvFiles(o)
 }
 
-// WithLoadOptions provides a hook to control how compose files are loaded
+// WithLoadOptions provides a hook to control how compose files are loaded.
+// Those are applied last, after all other ProjectOptions have been translated into loader.Options,
+// so they can set any loader option ProjectOptions doesn't expose.
+//
+// This is an advanced, unstable API: loader.Options may change without notice.
 func WithLoadOptions(loadOptions ...func(*loader.Options)) ProjectOptionsFn {
 	return func(o *ProjectOptions) error {
-		o.loadOptions = append(o.loadOptions, loadOptions...)
+		o.loadOptionsOverrides = append(o.loadOptionsOverrides, loadOptions...)
 		return nil
 	}
 }
 
-// WithDefaultProfiles uses the provided profiles (if any), and falls back to
-// profiles specified via the COMPOSE_PROFILES environment variable otherwise.
+// WithDefaultProfiles activates the provided profiles, merged with the comma-separated profiles set by the
+// COMPOSE_PROFILES variable. COMPOSE_PROFILES is read from the ProjectOptions environment, so it must be set by
+// a previous option like WithOsEnv or WithDotEnv, falling back to the OS environment. Empty entries are ignored.
 func WithDefaultProfiles(profile ...string) ProjectOptionsFn {
-	if len(profile) == 0 {
-		profile = strings.Split(os.Getenv(consts.ComposeProfiles), ",")
+	return func(o *ProjectOptions) error {
+		env, ok := o.Environment[consts.ComposeProfiles]
+		if !ok {
+			env = os.Getenv(consts.ComposeProfiles)
+		}
+		var profiles []string
+		for _, p := range append(slices.Clone(profile), strings.Split(env, ",")...) {
+			p = strings.TrimSpace(p)
+			if p != "" && !slices.Contains(profiles, p) {
+				profiles = append(profiles, p)
+			}
+		}
+		return WithProfiles(profiles)(o)
 	}
-	return WithProfiles(profile)
 }
 
 // WithProfiles sets profiles to be activated
@@ -405,6 +519,31 @@ func (o ProjectOptions) GetWorkingDir() (string, error) {
 	return os.Getwd()
 }
 
+// ProjectName returns the project name as resolved from options, applying the same precedence and normalization
+// rules as ProjectFromOptions: explicit name, then COMPOSE_PROJECT_NAME, then working directory base name.
+// As compose files are not parsed, a `name` declared by the compose files is not considered.
+func (o ProjectOptions) ProjectName() (string, error) {
+	workingDir, err := o.GetWorkingDir()
+	if err != nil {
+		return "", err
+	}
+	absWorkingDir, err := filepath.Abs(workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	var opts loader.Options
+	withNamePrecedenceLoad(absWorkingDir, &o)(&opts)
+	name, imperativelySet := opts.GetProjectName()
+	if imperativelySet && loader.NormalizeProjectName(name) != name {
+		return "", loader.InvalidProjectNameErr(name)
+	}
+	if name == "" {
+		return "", errors.New("project name must not be empty")
+	}
+	return name, nil
+}
+
 // ProjectFromOptions load a compose project based on command line options
 func ProjectFromOptions(options *ProjectOptions) (*types.Project, error) {
 	configPaths, err := getConfigPathsFromOptions(options)
@@ -445,10 +584,11 @@ func ProjectFromOptions(options *ProjectOptions) (*types.Project, error) {
 		return nil, err
 	}
 
-	options.loadOptions = append(options.loadOptions,
+	loadOptions := append(slices.Clone(options.loadOptions),
 		withNamePrecedenceLoad(absWorkingDir, options),
 		withConvertWindowsPaths(options),
 		withListener(options))
+	loadOptions = append(loadOptions, options.loadOptionsOverrides...)
 
 	ctx := options.ctx
 	if ctx == nil {
@@ -459,7 +599,7 @@ func ProjectFromOptions(options *ProjectOptions) (*types.Project, error) {
 		ConfigFiles: configs,
 		WorkingDir:  workingDir,
 		Environment: options.Environment,
-	}, options.loadOptions...)
+	}, loadOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -26,6 +26,8 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/compose-spec/compose-go/v2/consts"
+	"github.com/compose-spec/compose-go/v2/errdefs"
+	"github.com/compose-spec/compose-go/v2/loader"
 	"github.com/compose-spec/compose-go/v2/utils"
 )
 
@@ -90,10 +92,11 @@ func TestProjectName(t *testing.T) {
 	})
 
 	t.Run("by name start with invalid char '-'", func(t *testing.T) {
-		_, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("-my_project"))
-		assert.ErrorContains(t, err, `invalid project name "-my_project"`)
+		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("-my_project"))
+		assert.NilError(t, err)
+		assert.Equal(t, opts.Name, "my_project")
 
-		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
+		opts, err = NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
 			fmt.Sprintf("%s=%s", consts.ComposeProjectName, "-my_project"),
 		}))
 		assert.NilError(t, err)
@@ -103,10 +106,11 @@ func TestProjectName(t *testing.T) {
 	})
 
 	t.Run("by name start with invalid char '_'", func(t *testing.T) {
-		_, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("_my_project"))
-		assert.ErrorContains(t, err, `invalid project name "_my_project"`)
+		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("_my_project"))
+		assert.NilError(t, err)
+		assert.Equal(t, opts.Name, "my_project")
 
-		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
+		opts, err = NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
 			fmt.Sprintf("%s=%s", consts.ComposeProjectName, "_my_project"),
 		}))
 		assert.NilError(t, err)
@@ -116,10 +120,11 @@ func TestProjectName(t *testing.T) {
 	})
 
 	t.Run("by name contains dots", func(t *testing.T) {
-		_, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("www.my.project"))
-		assert.ErrorContains(t, err, `invalid project name "www.my.project"`)
+		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("www.my.project"))
+		assert.NilError(t, err)
+		assert.Equal(t, opts.Name, "wwwmyproject")
 
-		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
+		opts, err = NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
 			fmt.Sprintf("%s=%s", consts.ComposeProjectName, "www.my.project"),
 		}))
 		assert.NilError(t, err)
@@ -128,11 +133,27 @@ func TestProjectName(t *testing.T) {
 		assert.Assert(t, p == nil)
 	})
 
+	t.Run("by name without any valid char", func(t *testing.T) {
+		_, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("__.."))
+		assert.ErrorContains(t, err, `invalid project name "__.."`)
+	})
+
+	t.Run("by name takes precedence over environment", func(t *testing.T) {
+		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"},
+			WithEnv([]string{fmt.Sprintf("%s=%s", consts.ComposeProjectName, "from_env")}),
+			WithName("From.Code"))
+		assert.NilError(t, err)
+		p, err := ProjectFromOptions(opts)
+		assert.NilError(t, err)
+		assert.Equal(t, p.Name, "fromcode")
+	})
+
 	t.Run("by name uppercase", func(t *testing.T) {
-		_, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("MY_PROJECT"))
-		assert.ErrorContains(t, err, `invalid project name "MY_PROJECT"`)
+		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithName("MY_PROJECT"))
+		assert.NilError(t, err)
+		assert.Equal(t, opts.Name, "my_project")
 
-		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
+		opts, err = NewProjectOptions([]string{"testdata/simple/compose.yaml"}, WithEnv([]string{
 			fmt.Sprintf("%s=%s", consts.ComposeProjectName, "MY_PROJECT"),
 		}))
 		assert.NilError(t, err)
@@ -165,6 +186,28 @@ func TestProjectName(t *testing.T) {
 		assert.Equal(t, p.Name, "unnormalizedpath")
 	})
 
+	t.Run("by compose file name", func(t *testing.T) {
+		opts, err := NewProjectOptions([]string{"testdata/named/compose.yaml"})
+		assert.NilError(t, err)
+		p, err := ProjectFromOptions(opts)
+		assert.NilError(t, err)
+		assert.Equal(t, p.Name, "from_file")
+
+		opts, err = NewProjectOptions([]string{"testdata/named/compose.yaml"}, WithEnv([]string{
+			fmt.Sprintf("%s=%s", consts.ComposeProjectName, "from_env"),
+		}))
+		assert.NilError(t, err)
+		p, err = ProjectFromOptions(opts)
+		assert.NilError(t, err)
+		assert.Equal(t, p.Name, "from_env")
+
+		opts, err = NewProjectOptions([]string{"testdata/named/compose.yaml"}, WithName("from_code"))
+		assert.NilError(t, err)
+		p, err = ProjectFromOptions(opts)
+		assert.NilError(t, err)
+		assert.Equal(t, p.Name, "from_code")
+	})
+
 	t.Run("by COMPOSE_PROJECT_NAME", func(t *testing.T) {
 		os.Setenv("COMPOSE_PROJECT_NAME", "my_project_from_env") //nolint:errcheck
 		defer os.Unsetenv("COMPOSE_PROJECT_NAME")                //nolint:errcheck
@@ -241,6 +284,46 @@ func TestProjectComposefilesFromWorkingDir(t *testing.T) {
 	})
 }
 
+func TestProjectComposefilesFromGlob(t *testing.T) {
+	opts, err := NewProjectOptions(nil,
+		WithWorkingDirectory("testdata/glob/"),
+		WithName("my_project"),
+		WithConfigPathsFromGlob("*compose*", ".*", "*~"),
+	)
+	assert.NilError(t, err)
+	wd, err := filepath.Abs(filepath.Join("testdata", "glob"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, opts.ConfigPaths, []string{
+		filepath.Join(wd, "compose.yaml"),
+		filepath.Join(wd, "compose.dev.yaml"),
+		filepath.Join(wd, "compose.prod.yaml"),
+	})
+
+	p, err := ProjectFromOptions(opts)
+	assert.NilError(t, err)
+	service, err := p.GetService("app")
+	assert.NilError(t, err)
+	assert.Equal(t, service.Image, "app:prod")
+	assert.Equal(t, *service.Environment["STAGE"], "dev")
+
+	_, err = NewProjectOptions(nil,
+		WithWorkingDirectory("testdata/glob/"),
+		WithConfigPathsFromGlob("*.json"),
+	)
+	assert.Check(t, errdefs.IsNotFoundError(err))
+}
+
+func TestProjectComposefilesFromGlobIsReusable(t *testing.T) {
+	fromGlob := WithConfigPathsFromGlob("compose.yaml")
+	for _, dir := range []string{"glob", "simple"} {
+		opts, err := NewProjectOptions(nil, WithWorkingDirectory(filepath.Join("testdata", dir)), fromGlob)
+		assert.NilError(t, err)
+		wd, err := filepath.Abs(filepath.Join("testdata", dir))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, opts.ConfigPaths, []string{filepath.Join(wd, "compose.yaml")})
+	}
+}
+
 func TestProjectWithDotEnv(t *testing.T) {
 	wd, err := os.Getwd()
 	assert.NilError(t, err)
@@ -259,10 +342,10 @@ func TestProjectWithDotEnv(t *testing.T) {
 	assert.Equal(t, service.Ports[0].Published, "8000")
 }
 
-func TestProjectWithDiscardEnvFile(t *testing.T) {
+func TestProjectWithDiscardEnvFiles(t *testing.T) {
 	opts, err := NewProjectOptions([]string{
 		"testdata/env-file/compose-with-env-file.yaml",
-	}, WithDiscardEnvFile)
+	}, WithDiscardEnvFiles)
 
 	assert.NilError(t, err)
 	p, err := ProjectFromOptions(opts)
@@ -274,10 +357,100 @@ func TestProjectWithDiscardEnvFile(t *testing.T) {
 	assert.Equal(t, service.Ports[0].Published, "8000")
 }
 
+func TestProjectWithDeprecatedDiscardEnvFile(t *testing.T) {
+	opts, err := NewProjectOptions([]string{
+		"testdata/env-file/compose-with-env-file.yaml",
+	}, WithDiscardEnvFile) //nolint:staticcheck
+
+	assert.NilError(t, err)
+	p, err := ProjectFromOptions(opts)
+	assert.NilError(t, err)
+	service, err := p.GetService("simple")
+	assert.NilError(t, err)
+	assert.Equal(t, *service.Environment["DEFAULT_PORT"], "8080")
+	assert.Assert(t, len(service.EnvFiles) == 0)
+}
+
+func TestProjectWithDiscardEnvFilesNotResolved(t *testing.T) {
+	// env_file is only discarded once resolved into environment
+	opts, err := NewProjectOptions([]string{
+		"testdata/env-file/compose-with-env-file.yaml",
+	}, WithDiscardEnvFiles, WithResolvedPaths(false), WithLoadOptions(func(o *loader.Options) {
+		o.SkipResolveEnvironment = true
+	}))
+	assert.NilError(t, err)
+	p, err := ProjectFromOptions(opts)
+	assert.NilError(t, err)
+	service, err := p.GetService("simple")
+	assert.NilError(t, err)
+	assert.Equal(t, len(service.EnvFiles), 1)
+	assert.Check(t, service.Environment["DEFAULT_PORT"] == nil)
+}
+
+func TestProjectWithLoadOptions(t *testing.T) {
+	opts, err := NewProjectOptions([]string{
+		"testdata/simple/compose.yaml",
+	},
+		WithLoadOptions(func(o *loader.Options) {
+			// overrides the name set by WithName, as load options are applied last
+			o.SetProjectName("from_load_options", true)
+		}),
+		WithName("my_project"),
+		WithLoadOptions(func(o *loader.Options) {
+			o.SkipNormalization = true
+		}),
+	)
+	assert.NilError(t, err)
+	p, err := ProjectFromOptions(opts)
+	assert.NilError(t, err)
+	assert.Equal(t, p.Name, "from_load_options")
+	// normalization would have set the default network
+	assert.Equal(t, len(p.Networks), 0)
+}
+
+func TestProjectFromOptionsIsRepeatable(t *testing.T) {
+	opts, err := NewProjectOptions([]string{
+		"testdata/simple/compose.yaml",
+	}, WithName("my_project"))
+	assert.NilError(t, err)
+	loadOptions := len(opts.loadOptions)
+	for i := 0; i < 2; i++ {
+		p, err := ProjectFromOptions(opts)
+		assert.NilError(t, err)
+		assert.Equal(t, p.Name, "my_project")
+		assert.Equal(t, len(opts.loadOptions), loadOptions)
+	}
+}
+
+func TestProjectOptionsClone(t *testing.T) {
+	base, err := NewProjectOptions([]string{
+		"testdata/simple/compose.yaml",
+	}, WithName("my_project"), WithEnv([]string{"FOO=base"}), WithDiscardEnvFiles)
+	assert.NilError(t, err)
+
+	clone := base.Clone()
+	clone.Environment["FOO"] = "clone"
+	clone.Environment["BAR"] = "clone"
+	clone.ConfigPaths[0] = "testdata/simple/compose-with-overrides.yaml"
+	assert.NilError(t, WithName("other_project")(clone))
+	assert.NilError(t, WithProfiles([]string{"debug"})(clone))
+
+	assert.Equal(t, base.Name, "my_project")
+	assert.DeepEqual(t, base.Environment, types.Mapping{"FOO": "base"})
+	assert.DeepEqual(t, base.ConfigPaths, []string{"testdata/simple/compose.yaml"})
+	assert.Equal(t, len(base.loadOptions), 1)
+	assert.Equal(t, len(clone.loadOptions), 2)
+
+	p, err := ProjectFromOptions(base)
+	assert.NilError(t, err)
+	assert.Equal(t, p.Name, "my_project")
+	assert.Equal(t, len(p.Profiles), 0)
+}
+
 func TestProjectWithMultipleEnvFile(t *testing.T) {
 	opts, err := NewProjectOptions([]string{
 		"testdata/env-file/compose-with-env-files.yaml",
-	}, WithDiscardEnvFile,
+	}, WithDiscardEnvFiles,
 		WithEnvFiles("testdata/env-file/.env", "testdata/env-file/override.env"),
 		WithDotEnv)
 
@@ -291,6 +464,87 @@ func TestProjectWithMultipleEnvFile(t *testing.T) {
 	assert.Equal(t, service.Ports[0].Published, "9000")
 }
 
+func TestProjectWithInheritedEnvFile(t *testing.T) {
+	opts, err := NewProjectOptions([]string{
+		"testdata/env-file/compose-with-inherited-env.yaml",
+	}, WithName("inherited"),
+		WithEnv([]string{"IMAGE=from_host"}),
+		WithEnvFiles("testdata/env-file/inherited.env"),
+		WithDotEnv)
+	assert.NilError(t, err)
+	assert.Equal(t, opts.Environment["IMAGE"], "from_host")
+	// bare key not set by the host environment is omitted
+	_, ok := opts.Environment["TAG"]
+	assert.Assert(t, !ok)
+
+	p, err := ProjectFromOptions(opts)
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["simple"].Image, "from_host:latest")
+}
+
+func TestProjectOptionsProjectName(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ProjectOptionsFn
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "by name",
+			options:  []ProjectOptionsFn{WithName("my_project")},
+			expected: "my_project",
+		},
+		{
+			name: "by environment",
+			options: []ProjectOptionsFn{WithEnv([]string{
+				fmt.Sprintf("%s=%s", consts.ComposeProjectName, "my_project_env"),
+			})},
+			expected: "my_project_env",
+		},
+		{
+			name: "name takes precedence over environment",
+			options: []ProjectOptionsFn{WithName("my_project"), WithEnv([]string{
+				fmt.Sprintf("%s=%s", consts.ComposeProjectName, "my_project_env"),
+			})},
+			expected: "my_project",
+		},
+		{
+			name:     "by working dir",
+			options:  []ProjectOptionsFn{WithWorkingDirectory("/path/to/My.Project")},
+			expected: "myproject",
+		},
+		{
+			name:     "by config file directory",
+			expected: "simple",
+		},
+		{
+			name: "invalid name from environment",
+			options: []ProjectOptionsFn{WithEnv([]string{
+				fmt.Sprintf("%s=%s", consts.ComposeProjectName, "-my_project"),
+			})},
+			wantErr: `invalid project name "-my_project"`,
+		},
+		{
+			name:    "root directory",
+			options: []ProjectOptionsFn{WithWorkingDirectory("/")},
+			wantErr: "project name must not be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, tt.options...)
+			assert.NilError(t, err)
+			name, err := opts.ProjectName()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, name, tt.expected)
+		})
+	}
+}
+
 func TestProjectNameFromWorkingDir(t *testing.T) {
 	opts, err := NewProjectOptions([]string{
 		"testdata/env-file/compose-with-env-file.yaml",
@@ -352,3 +606,32 @@ func TestEnvVariablePrecedence(t *testing.T) {
 		})
 	}
 }
+
+func TestProjectWithDefaultProfiles(t *testing.T) {
+	profiles := func(options ...ProjectOptionsFn) []string {
+		opts, err := NewProjectOptions([]string{"testdata/simple/compose.yaml"}, options...)
+		assert.NilError(t, err)
+		var loadOptions loader.Options
+		for _, o := range opts.loadOptions {
+			o(&loadOptions)
+		}
+		return loadOptions.Profiles
+	}
+
+	assert.DeepEqual(t, profiles(
+		WithEnv([]string{"COMPOSE_PROFILES=debug, ,test,"}),
+		WithDefaultProfiles(),
+	), []string{"debug", "test"})
+
+	assert.DeepEqual(t, profiles(
+		WithEnv([]string{"COMPOSE_PROFILES=debug,test"}),
+		WithDefaultProfiles("frontend", "debug"),
+	), []string{"frontend", "debug", "test"})
+
+	t.Setenv("COMPOSE_PROFILES", "from-os")
+	assert.DeepEqual(t, profiles(WithDefaultProfiles("frontend")), []string{"frontend", "from-os"})
+	assert.DeepEqual(t, profiles(
+		WithEnv([]string{"COMPOSE_PROFILES="}),
+		WithDefaultProfiles(),
+	), []string(nil))
+}
@@ -33,11 +33,13 @@ Usage: compose-spec [OPTIONS] COMPOSE_FILE [COMPOSE_OVERRIDE_FILE]`)
 	}
 
 	var skipInterpolation, skipResolvePaths, skipNormalization, skipConsistencyCheck bool
+	var format string
 
 	flag.BoolVar(&skipInterpolation, "no-interpolation", false, "Don't interpolate environment variables.")
 	flag.BoolVar(&skipResolvePaths, "no-path-resolution", false, "Don't resolve file paths.")
 	flag.BoolVar(&skipNormalization, "no-normalization", false, "Don't normalize compose model.")
 	flag.BoolVar(&skipConsistencyCheck, "no-consistency", false, "Don't check model consistency.")
+	flag.StringVar(&format, "format", "yaml", "Format the resolved compose model as yaml or json.")
 	flag.Parse()
 
 	wd, err := os.Getwd()
@@ -65,11 +67,9 @@ Usage: compose-spec [OPTIONS] COMPOSE_FILE [COMPOSE_OVERRIDE_FILE]`)
 		exitError("failed to load project", err)
 	}
 
-	yaml, err := project.MarshalYAML()
-	if err != nil {
+	if err := project.Encode(os.Stdout, format); err != nil {
 		exitError("failed to marshall project", err)
 	}
-	fmt.Println(string(yaml))
 }
 
 func exitError(message string, err error) {
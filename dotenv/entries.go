@@ -0,0 +1,136 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package dotenv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EntryKind is the kind of statement an Entry represents
+type EntryKind int
+
+const (
+	// EntryBlank is an empty line
+	EntryBlank EntryKind = iota
+	// EntryComment is a full line comment
+	EntryComment
+	// EntryVariable is a variable declaration, possibly followed by an inline comment
+	EntryVariable
+)
+
+// Entry is a statement of an env file, as returned by ParseWithComments
+type Entry struct {
+	Kind EntryKind
+	// Key is the name of the variable, for EntryVariable
+	Key string
+	// Value is the unquoted value of the variable, for EntryVariable. Variables are not expanded
+	Value string
+	// Inherited is set for a variable declared without a value, which inherits its value from the environment
+	Inherited bool
+	// Export is set for a variable declared with the `export` prefix
+	Export bool
+	// Comment is the text following `#`, for EntryComment or an inline comment of an EntryVariable
+	Comment string
+}
+
+// ParseWithComments reads an env file from io.Reader, returning the sequence of statements it declares, including
+// comments and blank lines, so that it can be edited and written back without losing user annotations.
+func ParseWithComments(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser()
+	p.expand = false
+	return p.parseEntries(strings.TrimPrefix(string(data), utf8BOM))
+}
+
+func (p *parser) parseEntries(src string) ([]Entry, error) {
+	var entries []Entry
+	for src != "" {
+		line, rest, _ := strings.Cut(src, "\n")
+		statement := strings.TrimLeftFunc(line, isSpace)
+		switch {
+		case statement == "":
+			entries = append(entries, Entry{Kind: EntryBlank})
+			src = rest
+			p.line++
+			continue
+		case statement[0] == charComment:
+			entries = append(entries, Entry{Kind: EntryComment, Comment: strings.TrimSuffix(statement[1:], "\r")})
+			src = rest
+			p.line++
+			continue
+		}
+
+		src = strings.TrimLeftFunc(src, isSpace)
+		entry := Entry{
+			Kind:   EntryVariable,
+			Export: exportRegex.MatchString(src),
+		}
+		key, left, inherited, err := p.locateKeyName(src)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(key, " ") {
+			return nil, fmt.Errorf("line %d: key cannot contain a space", p.line)
+		}
+		entry.Key = key
+
+		if inherited {
+			entry.Inherited = true
+			entries = append(entries, entry)
+			src = left
+			p.line++
+			continue
+		}
+
+		_, isQuoted := hasQuotePrefix(left)
+		if !isQuoted {
+			value, _, _ := strings.Cut(left, "\n")
+			if _, comment, ok := strings.Cut(value, " #"); ok {
+				entry.Comment = strings.TrimSuffix(comment, "\r")
+			}
+		}
+		value, left, err := p.extractVarValue(left, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		entry.Value = value
+
+		if isQuoted {
+			// consume the remainder of the line after closing quote, which may hold an inline comment
+			trailing, rest, found := strings.Cut(left, "\n")
+			trailing = strings.TrimLeftFunc(trailing, isSpace)
+			if trailing == "" || trailing[0] == charComment {
+				if trailing != "" {
+					entry.Comment = strings.TrimSuffix(trailing[1:], "\r")
+				}
+				left = rest
+				if found {
+					p.line++
+				}
+			}
+		}
+		entries = append(entries, entry)
+		src = left
+	}
+	return entries, nil
+}
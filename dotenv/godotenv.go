@@ -14,7 +14,6 @@
 package dotenv
 
 import (
-	"bytes"
 	"io"
 	"os"
 	"regexp"
@@ -23,7 +22,7 @@ import (
 	"github.com/compose-spec/compose-go/v2/template"
 )
 
-var utf8BOM = []byte("\uFEFF")
+const utf8BOM = "\uFEFF"
 
 var startsWithDigitRegex = regexp.MustCompile(`^\s*\d.*`) // Keys starting with numbers are ignored
 
@@ -46,13 +45,69 @@ func ParseWithLookup(r io.Reader, lookupFn LookupFn) (map[string]string, error)
 		return nil, err
 	}
 
-	// seek past the UTF-8 BOM if it exists (particularly on Windows, some
-	// editors tend to add it, and it'll cause parsing to fail)
-	data = bytes.TrimPrefix(data, utf8BOM)
+	return UnmarshalBytesWithLookup(data, lookupFn)
+}
 
+// ReadBytes parses an env file content, returning a map of keys and values, like Parse does for an io.Reader.
+func ReadBytes(data []byte) (map[string]string, error) {
+	return ReadBytesWithLookup(nil, data)
+}
+
+// ReadBytesWithLookup parses an env file content, returning a map of keys and values, like ParseWithLookup does
+// for an io.Reader.
+func ReadBytesWithLookup(lookupFn LookupFn, data []byte) (map[string]string, error) {
 	return UnmarshalBytesWithLookup(data, lookupFn)
 }
 
+// ParseOptions controls how ParseWithOptions reads an env file
+type ParseOptions struct {
+	// NoExpand disables variable expansion of unquoted and double-quoted values, which Parse does by default.
+	// Values are then read literally, so `$FOO` and `${BAR}` are preserved, while quoting and escape sequences
+	// still apply.
+	NoExpand bool
+	// LookupFn resolves variables during expansion.
+	// By default, LookupFn takes precedence over values previously defined by the env file itself, so that
+	// the shell environment can override them. See FilePrecedence to use LookupFn only as a fallback.
+	LookupFn LookupFn
+	// FilePrecedence makes values previously defined by the env file take precedence over LookupFn, which is
+	// then only used to resolve variables the file doesn't define.
+	FilePrecedence bool
+	// OnDuplicate selects how a key declared more than once by the env file is handled, defaults to LastWins
+	OnDuplicate DuplicatePolicy
+	// TwoPass reads all values before expanding them, so a value can reference a key declared later in the file.
+	// Values defined by the file then take precedence over LookupFn, which is only used as a fallback, and a cyclic
+	// reference between keys is reported as an error.
+	// By default, values are expanded top to bottom, as a shell does, so forward references resolve by LookupFn.
+	TwoPass bool
+}
+
+// DuplicatePolicy selects how ParseWithOptions handles a key declared more than once
+type DuplicatePolicy int
+
+const (
+	// LastWins keeps the value of the last declaration of a key
+	LastWins DuplicatePolicy = iota
+	// FirstWins keeps the value of the first declaration of a key, ignoring later ones
+	FirstWins
+	// ErrorOnDuplicate reports a key declared more than once as an error, with the lines declaring it
+	ErrorOnDuplicate
+)
+
+// ParseWithOptions reads an env file from io.Reader, returning a map of keys and values.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser()
+	p.expand = !opts.NoExpand
+	p.filePrecedence = opts.FilePrecedence
+	p.onDuplicate = opts.OnDuplicate
+	p.twoPass = opts.TwoPass
+	return unmarshal(string(data), p, opts.LookupFn)
+}
+
 // Load will read your env file(s) and load them into ENV for this process.
 //
 // Call this function as close as possible to the start of your program (ideally in main).
@@ -80,13 +135,21 @@ func load(overload bool, filenames ...string) error {
 }
 
 // ReadWithLookup gets all env vars from the files and/or lookup function and return values as
-// a map rather than automatically writing values into env
+// a map rather than automatically writing values into env.
+// During expansion, lookupFn takes precedence over values defined by the file, see ReadWithOptions to
+// make the file authoritative.
 func ReadWithLookup(lookupFn LookupFn, filenames ...string) (map[string]string, error) {
+	return ReadWithOptions(ParseOptions{LookupFn: lookupFn}, filenames...)
+}
+
+// ReadWithOptions gets all env vars from the files, parsed according to opts, and return values as
+// a map rather than automatically writing values into env
+func ReadWithOptions(opts ParseOptions, filenames ...string) (map[string]string, error) {
 	filenames = filenamesOrDefault(filenames)
 	envMap := make(map[string]string)
 
 	for _, filename := range filenames {
-		individualEnvMap, individualErr := readFile(filename, lookupFn)
+		individualEnvMap, individualErr := readFileWithOptions(filename, opts)
 
 		if individualErr != nil {
 			return envMap, individualErr
@@ -116,8 +179,16 @@ func UnmarshalBytesWithLookup(src []byte, lookupFn LookupFn) (map[string]string,
 
 // UnmarshalWithLookup parses env file from string, returning a map of keys and values.
 func UnmarshalWithLookup(src string, lookupFn LookupFn) (map[string]string, error) {
+	return unmarshal(src, newParser(), lookupFn)
+}
+
+func unmarshal(src string, p *parser, lookupFn LookupFn) (map[string]string, error) {
+	// seek past the UTF-8 BOM if it exists (particularly on Windows, some
+	// editors tend to add it, and it'll cause parsing to fail)
+	src = strings.TrimPrefix(src, utf8BOM)
+
 	out := make(map[string]string)
-	err := newParser().parse(src, out, lookupFn)
+	err := p.parse(src, out, lookupFn)
 	return out, err
 }
 
@@ -151,13 +222,17 @@ func loadFile(filename string, overload bool) error {
 }
 
 func readFile(filename string, lookupFn LookupFn) (map[string]string, error) {
+	return readFileWithOptions(filename, ParseOptions{LookupFn: lookupFn})
+}
+
+func readFileWithOptions(filename string, opts ParseOptions) (map[string]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	return ParseWithLookup(file, lookupFn)
+	return ParseWithOptions(file, opts)
 }
 
 func expandVariables(value string, envMap map[string]string, lookupFn LookupFn) (string, error) {
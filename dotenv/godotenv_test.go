@@ -322,6 +322,12 @@ func TestParsing(t *testing.T) {
 	// parses single quoted values
 	parseAndCompare(t, "FOO='bar'", "FOO", "bar")
 
+	// parses backtick quoted values literally, including across lines
+	parseAndCompare(t, "FOO=`bar`", "FOO", "bar")
+	parseAndCompare(t, "FOO=`$BAR \\n \"baz\" 'qux'`", "FOO", `$BAR \n "baz" 'qux'`)
+	parseAndCompare(t, "FOO=`line1\n# not a comment\nline3`", "FOO", "line1\n# not a comment\nline3")
+	parseAndCompare(t, "FOO=`escaped\\`backtick`", "FOO", "escaped`backtick")
+
 	// parses escaped double quotes
 	parseAndCompare(t, `FOO="escaped\"bar"`, "FOO", `escaped"bar`)
 	parseAndCompare(t, `FOO="\"quoted\""`, "FOO", `"quoted"`)
@@ -399,8 +405,6 @@ func TestParsing(t *testing.T) {
 	parseAndCompare(t, `FOO="bar\\r\ b\az"`, "FOO", "bar\\r\\ b\az")
 	parseAndCompare(t, `FOO="bar\nbaz\\"`, "FOO", "bar\nbaz\\")
 
-	parseAndCompare(t, `="value"`, "", "value")
-
 	// leading whitespace should be ignored
 	parseAndCompare(t, " KEY =value", "KEY", "value")
 	parseAndCompare(t, "   KEY=value", "KEY", "value")
@@ -433,6 +437,10 @@ func TestUnterminatedQuotes(t *testing.T) {
 		`KEY='value`,
 		`KEY='value\'`,
 		`KEY='value"`,
+		"KEY=`",
+		"KEY=`value",
+		"KEY=`value\\`",
+		"KEY=`value'",
 	}
 	for _, tc := range cases {
 		_, err := Parse(strings.NewReader(tc))
@@ -611,6 +619,35 @@ func TestSubstitutionsWithEnvFilePrecedence(t *testing.T) {
 	}
 }
 
+func TestReadWithOptionsPrecedence(t *testing.T) {
+	lookup := func(key string) (string, bool) {
+		v, ok := map[string]string{
+			"REGISTRY": "docker.io",
+			"VERSION":  "1.2",
+		}[key]
+		return v, ok
+	}
+
+	envMap, err := ReadWithLookup(lookup, "fixtures/precedence.env")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, envMap, map[string]string{
+		"REGISTRY": "registry.local",
+		"IMAGE":    "docker.io/app",
+		"TAG":      "1.2",
+	})
+
+	envMap, err = ReadWithOptions(ParseOptions{
+		LookupFn:       lookup,
+		FilePrecedence: true,
+	}, "fixtures/precedence.env")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, envMap, map[string]string{
+		"REGISTRY": "registry.local",
+		"IMAGE":    "registry.local/app",
+		"TAG":      "1.2",
+	})
+}
+
 func TestSubstitutionsWithEnvFileDefaultValuePrecedence(t *testing.T) {
 	os.Clearenv()
 	const envKey = "OPTION_A"
@@ -691,6 +728,43 @@ func TestUTF8BOM(t *testing.T) {
 	loadEnvAndCompareValues(t, Load, envFileName, expectedValues, noopPresets)
 }
 
+func TestCRLF(t *testing.T) {
+	for _, envFileName := range []string{"fixtures/crlf.env", "fixtures/utf8-bom-crlf.env"} {
+		t.Run(envFileName, func(t *testing.T) {
+			// sanity check the fixture, as git might convert line endings
+			envFileData, err := os.ReadFile(envFileName)
+			require.NoError(t, err)
+			require.True(t, bytes.Contains(envFileData, []byte("\r\n")),
+				"Test fixture file is missing CRLF line endings")
+
+			envMap, err := ReadWithLookup(func(s string) (string, bool) {
+				if s == "OPTION_E" {
+					return "inherited", true
+				}
+				return "", false
+			}, envFileName)
+			require.NoError(t, err)
+			assert.DeepEqual(t, map[string]string{
+				"OPTION_A": "1",
+				"OPTION_B": "2",
+				"OPTION_C": "quoted",
+				"OPTION_D": "first line\r\nsecond line",
+				"OPTION_E": "inherited",
+				"OPTION_F": "6",
+			}, envMap)
+		})
+	}
+}
+
+func TestUnmarshalUTF8BOM(t *testing.T) {
+	envMap, err := UnmarshalWithLookup("\uFEFFOPTION_A=1\r\nOPTION_B=2\r\n", nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"OPTION_A": "1",
+		"OPTION_B": "2",
+	}, envMap)
+}
+
 func TestDash(t *testing.T) {
 	loadEnvAndCompareValues(t, Load, "fixtures/special.env", map[string]string{
 		"VAR-WITH-DASHES":      "dashes",
@@ -708,3 +782,191 @@ func TestGetEnvFromFile(t *testing.T) {
 	_, err = GetEnvFromFile(nil, []string{f})
 	assert.Check(t, strings.HasSuffix(err.Error(), ".env is a directory"))
 }
+
+func TestMarshal(t *testing.T) {
+	env := map[string]string{
+		"INT":       "42",
+		"ZEROS":     "007",
+		"SIMPLE":    "value",
+		"URL":       "https://example.com:8080/path",
+		"SPACES":    "hello world",
+		"TRAILING":  "value ",
+		"EMPTY":     "",
+		"QUOTES":    `say "hi"`,
+		"DOLLAR":    "$HOME",
+		"MULTILINE": "line1\nline2",
+		"BACKSLASH": `C:\path`,
+		"COMMENT":   "a #b",
+		"SINGLE":    "'quoted'",
+		"BACKTICK":  "`quoted`",
+	}
+
+	out, err := Marshal(env)
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(out, "INT=42\n"))
+	assert.Check(t, strings.Contains(out, `SIMPLE="value"`))
+
+	minimal, err := MarshalWithOptions(env, MarshalOptions{MinimalQuoting: true})
+	assert.NilError(t, err)
+	assert.Equal(t, minimal, `BACKSLASH="C:\\path"
+BACKTICK="`+"`quoted`"+`"
+COMMENT="a #b"
+DOLLAR="\$HOME"
+EMPTY=""
+INT=42
+MULTILINE="line1\nline2"
+QUOTES="say \"hi\""
+SIMPLE=value
+SINGLE="'quoted'"
+SPACES="hello world"
+TRAILING="value "
+URL=https://example.com:8080/path
+ZEROS=007`)
+
+	exported, err := MarshalWithOptions(map[string]string{"FOO": "bar", "EMPTY": ""}, MarshalOptions{Export: true})
+	assert.NilError(t, err)
+	assert.Equal(t, exported, "export EMPTY=\"\"\nexport FOO=\"bar\"")
+
+	exportedEnv, err := MarshalWithOptions(env, MarshalOptions{Export: true, MinimalQuoting: true})
+	assert.NilError(t, err)
+
+	for _, s := range []string{out, minimal, exportedEnv} {
+		parsed, err := UnmarshalWithLookup(s, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, parsed, env)
+	}
+}
+
+func TestParseWithComments(t *testing.T) {
+	src := `# database settings
+DB_HOST=localhost # local instance
+export DB_PORT=5432
+
+DB_PASSWORD="s3cr#t" # quoted
+DB_URL=${DB_HOST}:${DB_PORT}
+  # indented comment
+INHERITED
+MULTI="line1
+line2"
+`
+	entries, err := ParseWithComments(strings.NewReader(src))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, entries, []Entry{
+		{Kind: EntryComment, Comment: " database settings"},
+		{Kind: EntryVariable, Key: "DB_HOST", Value: "localhost", Comment: " local instance"},
+		{Kind: EntryVariable, Key: "DB_PORT", Value: "5432", Export: true},
+		{Kind: EntryBlank},
+		{Kind: EntryVariable, Key: "DB_PASSWORD", Value: "s3cr#t", Comment: " quoted"},
+		{Kind: EntryVariable, Key: "DB_URL", Value: "${DB_HOST}:${DB_PORT}"},
+		{Kind: EntryComment, Comment: " indented comment"},
+		{Kind: EntryVariable, Key: "INHERITED", Inherited: true},
+		{Kind: EntryVariable, Key: "MULTI", Value: "line1\nline2"},
+	})
+
+	_, err = ParseWithComments(strings.NewReader("FOO BAR=baz"))
+	assert.ErrorContains(t, err, "line 1: key cannot contain a space")
+
+	_, err = ParseWithComments(strings.NewReader("# comment\n\nFOO=\"unterminated"))
+	assert.ErrorContains(t, err, "line 3: unterminated quoted value")
+}
+
+func TestReadBytes(t *testing.T) {
+	env, err := ReadBytes([]byte("FOO=bar\nBAZ=${FOO}\n"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"FOO": "bar", "BAZ": "bar"})
+
+	env, err = ReadBytes(nil)
+	assert.NilError(t, err)
+	assert.Check(t, env != nil)
+	assert.Equal(t, len(env), 0)
+
+	lookup := func(key string) (string, bool) {
+		if key == "HOST" {
+			return "example.com", true
+		}
+		return "", false
+	}
+	env, err = ReadBytesWithLookup(lookup, []byte("URL=https://${HOST}/\nHOST"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"URL": "https://example.com/", "HOST": "example.com"})
+
+	_, err = ReadBytes([]byte(`FOO="unterminated`))
+	assert.ErrorContains(t, err, "unterminated quoted value")
+}
+
+func TestParseDuplicateKeys(t *testing.T) {
+	input := "# settings\nFOO=first\nBAR\nBAZ=baz\n\nFOO=second\n"
+	lookup := func(key string) (string, bool) {
+		if key == "BAR" {
+			return "bar", true
+		}
+		return "", false
+	}
+
+	env, err := ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"FOO": "second", "BAR": "bar", "BAZ": "baz"})
+
+	env, err = ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup, OnDuplicate: FirstWins})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"FOO": "first", "BAR": "bar", "BAZ": "baz"})
+
+	_, err = ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup, OnDuplicate: ErrorOnDuplicate})
+	assert.Error(t, err, "line 6: key FOO is already declared at line 2")
+
+	_, err = ParseWithOptions(strings.NewReader("FOO=\"multi\nline\"\nBAR\nexport FOO=again"), ParseOptions{OnDuplicate: ErrorOnDuplicate})
+	assert.Error(t, err, "line 4: key FOO is already declared at line 1")
+
+	// values are expanded as the file is read, so a reference resolves to the value declared so far
+	input = "FOO=first\nBAR=${FOO}\nFOO=second\nBAZ=${FOO}\n"
+	env, err = ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"FOO": "second", "BAR": "first", "BAZ": "second"})
+
+	env, err = ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup, OnDuplicate: FirstWins})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"FOO": "first", "BAR": "first", "BAZ": "first"})
+
+	env, err = ParseWithOptions(strings.NewReader(input), ParseOptions{NoExpand: true, OnDuplicate: FirstWins})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{"FOO": "first", "BAR": "${FOO}", "BAZ": "${FOO}"})
+}
+
+func TestParseTwoPass(t *testing.T) {
+	input := `URL=http://${HOST}:${PORT}/
+HOST=example.com
+QUOTED="${URL}api"
+LITERAL='${HOST}'
+ESCAPED=$${HOST}
+PATH=${PATH}:/opt/bin
+PORT=${DEFAULT_PORT:-8080}
+`
+	lookup := func(key string) (string, bool) {
+		switch key {
+		case "HOST":
+			return "shell.example.com", true
+		case "PATH":
+			return "/usr/bin", true
+		}
+		return "", false
+	}
+
+	env, err := ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup})
+	assert.NilError(t, err)
+	assert.Equal(t, env["URL"], "http://shell.example.com:/")
+
+	env, err = ParseWithOptions(strings.NewReader(input), ParseOptions{LookupFn: lookup, TwoPass: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, map[string]string{
+		"URL":     "http://example.com:8080/",
+		"HOST":    "example.com",
+		"QUOTED":  "http://example.com:8080/api",
+		"LITERAL": "${HOST}",
+		"ESCAPED": "${HOST}",
+		"PATH":    "/usr/bin:/opt/bin",
+		"PORT":    "8080",
+	})
+
+	_, err = ParseWithOptions(strings.NewReader("A=${B}\nB=${C}\nC=${A}\n"), ParseOptions{TwoPass: true})
+	assert.Error(t, err, "cyclic reference between variables: A -> B -> C -> A")
+}
@@ -1,6 +1,7 @@
 package dotenv
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/compose-spec/compose-go/v2/template"
@@ -153,3 +154,35 @@ func TestErrorIfUnset(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWithoutExpansion(t *testing.T) {
+	input := `UNQUOTED=$TEST_VAR
+BRACES=${TEST_VAR}
+DOUBLE_QUOTED="${TEST_VAR} and $TEST_VAR"
+SINGLE_QUOTED='$TEST_VAR'
+ESCAPED="\$TEST_VAR\n"
+REQUIRED=${UNSET_VAR?must be set}
+`
+	lookup := func(s string) (string, bool) {
+		v, ok := envMap[s]
+		return v, ok
+	}
+
+	result, err := ParseWithOptions(strings.NewReader(input), ParseOptions{NoExpand: true, LookupFn: lookup})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"UNQUOTED":      "$TEST_VAR",
+		"BRACES":        "${TEST_VAR}",
+		"DOUBLE_QUOTED": "${TEST_VAR} and $TEST_VAR",
+		"SINGLE_QUOTED": "$TEST_VAR",
+		"ESCAPED":       "$TEST_VAR\n",
+		"REQUIRED":      "${UNSET_VAR?must be set}",
+	}, result)
+
+	result, err = ParseWithOptions(strings.NewReader("FOO=$TEST_VAR\nBAR=\"\\$TEST_VAR\""), ParseOptions{LookupFn: lookup})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"FOO": "Test Value",
+		"BAR": "$TEST_VAR",
+	}, result)
+}
@@ -0,0 +1,81 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package dotenv
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarshalOptions controls how MarshalWithOptions formats an env file
+type MarshalOptions struct {
+	// MinimalQuoting leaves values which don't need to be quoted, like simple alphanumeric values, unquoted.
+	// Other values are double-quoted and escaped.
+	MinimalQuoting bool
+	// Export prefixes every line with `export `, so the file can be sourced by a shell.
+	// Parsing ignores this prefix.
+	Export bool
+}
+
+// safeValueRegex matches values which are parsed literally when unquoted
+var safeValueRegex = regexp.MustCompile(`^[A-Za-z0-9_.,:/@%+=-]*$`)
+
+// Marshal outputs the given environment as a dotenv-formatted environment file, sorted by key.
+// Each line is in the format: KEY="VALUE" where VALUE is backslash-escaped, integers being left unquoted.
+func Marshal(envMap map[string]string) (string, error) {
+	return MarshalWithOptions(envMap, MarshalOptions{})
+}
+
+// MarshalWithOptions outputs the given environment as a dotenv-formatted environment file, sorted by key.
+// Parsing the output reproduces the original environment.
+func MarshalWithOptions(envMap map[string]string, opts MarshalOptions) (string, error) {
+	lines := make([]string, 0, len(envMap))
+	prefix := ""
+	if opts.Export {
+		prefix = "export "
+	}
+	for k, v := range envMap {
+		lines = append(lines, prefix+k+"="+marshalValue(v, opts))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+func marshalValue(value string, opts MarshalOptions) string {
+	if d, err := strconv.Atoi(value); err == nil && strconv.Itoa(d) == value {
+		return value
+	}
+	if opts.MinimalQuoting && value != "" && safeValueRegex.MatchString(value) {
+		return value
+	}
+	return `"` + doubleQuoteEscape(value) + `"`
+}
+
+var doubleQuoteEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\n", `\n`,
+	"\r", `\r`,
+	`"`, `\"`,
+	`$`, `\$`,
+)
+
+// doubleQuoteEscape escapes characters with a special meaning within a double-quoted value
+func doubleQuoteEscape(value string) string {
+	return doubleQuoteEscaper.Replace(value)
+}
@@ -4,15 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
+
+	"github.com/compose-spec/compose-go/v2/template"
 )
 
 const (
 	charComment       = '#'
 	prefixSingleQuote = '\''
 	prefixDoubleQuote = '"'
+	prefixBacktick    = '`'
 )
 
 var (
@@ -22,11 +26,20 @@ var (
 
 type parser struct {
 	line int
+	// expand enables variable expansion in unquoted and double-quoted values
+	expand bool
+	// filePrecedence makes values already parsed from the file take precedence over lookupFn
+	filePrecedence bool
+	// onDuplicate selects how a key declared more than once is handled
+	onDuplicate DuplicatePolicy
+	// twoPass defers expansion until all values have been read, so values can reference keys declared later
+	twoPass bool
 }
 
 func newParser() *parser {
 	return &parser{
-		line: 1,
+		line:   1,
+		expand: true,
 	}
 }
 
@@ -35,6 +48,18 @@ func (p *parser) parse(src string, out map[string]string, lookupFn LookupFn) err
 	if lookupFn == nil {
 		lookupFn = noLookupFn
 	}
+	if p.filePrecedence {
+		fallback := lookupFn
+		lookupFn = func(key string) (string, bool) {
+			if v, ok := out[key]; ok {
+				return v, true
+			}
+			return fallback(key)
+		}
+	}
+	declared := map[string]int{}
+	// deferred tracks values which expansion is delayed by two-pass parsing
+	deferred := map[string]bool{}
 	for {
 		cutset = p.getStatementStart(cutset)
 		if cutset == "" {
@@ -42,6 +67,7 @@ func (p *parser) parse(src string, out map[string]string, lookupFn LookupFn) err
 			break
 		}
 
+		line := p.line
 		key, left, inherited, err := p.locateKeyName(cutset)
 		if err != nil {
 			return err
@@ -49,25 +75,103 @@ func (p *parser) parse(src string, out map[string]string, lookupFn LookupFn) err
 		if strings.Contains(key, " ") {
 			return fmt.Errorf("line %d: key cannot contain a space", p.line)
 		}
+		first, duplicate := declared[key]
+		if duplicate && p.onDuplicate == ErrorOnDuplicate {
+			return fmt.Errorf("line %d: key %s is already declared at line %d", line, key, first)
+		}
+		if !duplicate {
+			declared[key] = line
+		}
+		keep := !duplicate || p.onDuplicate != FirstWins
 
 		if inherited {
 			value, ok := lookupFn(key)
-			if ok {
+			if ok && keep {
 				out[key] = value
 			}
+			if left != "" {
+				p.line++
+			}
 			cutset = left
 			continue
 		}
 
+		quote, isQuoted := hasQuotePrefix(left)
 		value, left, err := p.extractVarValue(left, out, lookupFn)
 		if err != nil {
 			return err
 		}
 
-		out[key] = value
+		if keep {
+			out[key] = value
+			deferred[key] = p.twoPass && p.expand && (!isQuoted || quote == prefixDoubleQuote)
+		}
 		cutset = left
 	}
 
+	if p.twoPass {
+		return expandDeferred(out, deferred, lookupFn)
+	}
+	return nil
+}
+
+// expandDeferred expands values collected by a first pass, so they can reference any key of the file, whatever
+// the order of declarations. Values defined by the file take precedence over lookupFn, and a value referencing
+// its own key resolves it by lookupFn.
+func expandDeferred(out map[string]string, deferred map[string]bool, lookupFn LookupFn) error {
+	var (
+		resolving []string
+		failure   error
+		resolve   func(key string) error
+	)
+	mapping := func(name string) (string, bool) {
+		if name == resolving[len(resolving)-1] {
+			return lookupFn(name)
+		}
+		if err := resolve(name); err != nil {
+			if failure == nil {
+				failure = err
+			}
+			return "", false
+		}
+		if v, ok := out[name]; ok {
+			return v, true
+		}
+		return lookupFn(name)
+	}
+	resolve = func(key string) error {
+		if !deferred[key] {
+			return nil
+		}
+		for _, k := range resolving {
+			if k == key {
+				return fmt.Errorf("cyclic reference between variables: %s", strings.Join(append(resolving, key), " -> "))
+			}
+		}
+		resolving = append(resolving, key)
+		value, err := template.Substitute(out[key], mapping)
+		resolving = resolving[:len(resolving)-1]
+		if err != nil {
+			return err
+		}
+		if failure != nil {
+			return failure
+		}
+		out[key] = value
+		deferred[key] = false
+		return nil
+	}
+
+	keys := make([]string, 0, len(deferred))
+	for key := range deferred {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := resolve(key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -105,7 +209,10 @@ func (p *parser) locateKeyName(src string) (string, string, bool, error) {
 	}
 
 	// locate key name end and validate it in single loop
-	offset := 0
+	// a key without value at the end of file inherits its value, like a key followed by a line break
+	offset := len(src)
+	key = src
+	inherited = true
 loop:
 	for i, rune := range src {
 		if isSpace(rune) {
@@ -128,7 +235,7 @@ loop:
 
 			return "", "", inherited, fmt.Errorf(
 				`line %d: unexpected character %q in variable name %q`,
-				p.line, string(rune), strings.Split(src, "\n")[0])
+				p.line, string(rune), firstLine(src))
 		}
 	}
 
@@ -138,6 +245,9 @@ loop:
 
 	// trim whitespace
 	key = strings.TrimRightFunc(key, unicode.IsSpace)
+	if key == "" {
+		return "", "", inherited, fmt.Errorf(`line %d: missing variable name in %q`, p.line, firstLine(src))
+	}
 	cutset := strings.TrimLeftFunc(src[offset:], isSpace)
 	return key, cutset, inherited, nil
 }
@@ -153,6 +263,9 @@ func (p *parser) extractVarValue(src string, envMap map[string]string, lookupFn
 		// Remove inline comments on unquoted lines
 		value, _, _ = strings.Cut(value, " #")
 		value = strings.TrimRightFunc(value, unicode.IsSpace)
+		if !p.expand || p.twoPass {
+			return value, rest, nil
+		}
 		retVal, err := expandVariables(string(value), envMap, lookupFn)
 		return retVal, rest, err
 	}
@@ -178,7 +291,7 @@ func (p *parser) extractVarValue(src string, envMap map[string]string, lookupFn
 			continue
 		}
 
-		// skip escaped quote symbol (\" or \', depends on quote)
+		// skip escaped quote symbol (\", \' or \`, depends on quote)
 		if previousCharIsEscape {
 			previousCharIsEscape = false
 			chars = append(chars, char)
@@ -187,10 +300,15 @@ func (p *parser) extractVarValue(src string, envMap map[string]string, lookupFn
 
 		// trim quotes
 		value := string(chars)
-		if quote == prefixDoubleQuote {
+		if quote == prefixDoubleQuote && !p.expand {
+			value = expandEscapes(value, false)
+		} else if quote == prefixDoubleQuote && p.twoPass {
+			// variables are expanded once all values have been read
+			value = expandEscapes(value, true)
+		} else if quote == prefixDoubleQuote {
 			// expand standard shell escape sequences & then interpolate
 			// variables on the result
-			retVal, err := expandVariables(expandEscapes(value), envMap, lookupFn)
+			retVal, err := expandVariables(expandEscapes(value, true), envMap, lookupFn)
 			if err != nil {
 				return "", "", err
 			}
@@ -201,16 +319,22 @@ func (p *parser) extractVarValue(src string, envMap map[string]string, lookupFn
 	}
 
 	// return formatted error if quoted string is not terminated
-	valEndIndex := strings.IndexFunc(src, isCharFunc('\n'))
-	if valEndIndex == -1 {
-		valEndIndex = len(src)
-	}
+	return "", "", fmt.Errorf("line %d: unterminated quoted value %s", p.line, firstLine(src))
+}
 
-	return "", "", fmt.Errorf("line %d: unterminated quoted value %s", p.line, src[:valEndIndex])
+// firstLine returns the first line of src, without the line break (either `\n` or `\r\n`)
+func firstLine(src string) string {
+	line, _, _ := strings.Cut(src, "\n")
+	return strings.TrimSuffix(line, "\r")
 }
 
-func expandEscapes(str string) string {
+// expandEscapes replaces escape sequences by the actual characters. `\$` is kept as `$$` when value will be
+// interpolated, so it is not considered a variable
+func expandEscapes(str string, interpolated bool) string {
 	out := escapeSeqRegex.ReplaceAllStringFunc(str, func(match string) string {
+		if match == `\$` && !interpolated {
+			return "$"
+		}
 		if match == `\$` {
 			// `\$` is not a Go escape sequence, the expansion parser uses
 			// the special `$$` syntax
@@ -250,14 +374,15 @@ func (p *parser) indexOfNonSpaceChar(src string) int {
 	})
 }
 
-// hasQuotePrefix reports whether charset starts with single or double quote and returns quote character
+// hasQuotePrefix reports whether charset starts with single, double or backtick quote and returns quote character.
+// Like single-quoted ones, backtick-quoted values are taken literally.
 func hasQuotePrefix(src string) (byte, bool) {
 	if src == "" {
 		return 0, false
 	}
 
 	switch quote := src[0]; quote {
-	case prefixDoubleQuote, prefixSingleQuote:
+	case prefixDoubleQuote, prefixSingleQuote, prefixBacktick:
 		return quote, true // isQuoted
 	default:
 		return 0, false
@@ -42,6 +42,19 @@ func TestParseVariable(t *testing.T) {
 
 }
 
+func TestParseMissingVariableName(t *testing.T) {
+	for _, input := range []string{`="value"`, " =value", "FOO=bar\n\t: value", "export =value"} {
+		err := newParser().parse(input, map[string]string{}, nil)
+		assert.ErrorContains(t, err, "missing variable name", input)
+	}
+
+	// dots are supported in variable names
+	out := map[string]string{}
+	err := newParser().parse(" KEY.WITH.DOT =value", out, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, out, map[string]string{"KEY.WITH.DOT": "value"})
+}
+
 func TestMemoryExplosion(t *testing.T) {
 	p := newParser()
 	var startMemStats runtime.MemStats
@@ -62,3 +75,11 @@ func TestMemoryExplosion(t *testing.T) {
 		"memory usage should be linear with input size. Memory grew by: %d",
 		endMemStats.Alloc-startMemStats.Alloc)
 }
+
+func TestParseErrorWithCRLF(t *testing.T) {
+	err := newParser().parse("FOO=bar\r\nlol$wut\r\n", map[string]string{}, nil)
+	assert.Error(t, err, "line 2: unexpected character \"$\" in variable name \"lol$wut\"")
+
+	err = newParser().parse("FOO=\"bar\r\n", map[string]string{}, nil)
+	assert.Error(t, err, "line 2: unterminated quoted value \"bar")
+}
@@ -21,7 +21,6 @@ import (
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/utils"
-	"golang.org/x/exp/slices"
 )
 
 // graph represents project as service dependencies
@@ -72,29 +71,54 @@ func (g *graph[T]) leaves() []*vertex[T] {
 	return res
 }
 
+// checkCycle runs an iterative depth-first search on the graph, so that large graphs can't exhaust the stack,
+// and reports the first cycle found as a path of vertices, e.g. `web -> db -> web`
 func (g *graph[T]) checkCycle() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	type frame struct {
+		key      string
+		children []string
+		next     int
+	}
+	state := make(map[string]int, len(g.vertices))
 	// iterate on vertices in a name-order to render a predicable error message
 	// this is required by tests and enforce command reproducibility by user, which otherwise could be confusing
-	names := utils.MapKeys(g.vertices)
-	for _, name := range names {
-		err := searchCycle([]string{name}, g.vertices[name])
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func searchCycle[T any](path []string, v *vertex[T]) error {
-	names := utils.MapKeys(v.children)
-	for _, name := range names {
-		if i := slices.Index(path, name); i > 0 {
-			return fmt.Errorf("dependency cycle detected: %s", strings.Join(path[i:], " -> "))
+	for _, name := range utils.MapKeys(g.vertices) {
+		if state[name] != unvisited {
+			continue
 		}
-		ch := v.children[name]
-		err := searchCycle(append(path, name), ch)
-		if err != nil {
-			return err
+		state[name] = visiting
+		stack := []*frame{{key: name, children: utils.MapKeys(g.vertices[name].children)}}
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.next == len(top.children) {
+				state[top.key] = visited
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			child := top.children[top.next]
+			top.next++
+			switch state[child] {
+			case visiting:
+				// child is on the stack, so the path from child to top closes a cycle
+				start := len(stack) - 1
+				for stack[start].key != child {
+					start--
+				}
+				path := make([]string, 0, len(stack)-start+1)
+				for _, f := range stack[start:] {
+					path = append(path, f.key)
+				}
+				path = append(path, child)
+				return fmt.Errorf("dependency cycle detected: %s", strings.Join(path, " -> "))
+			case unvisited:
+				state[child] = visiting
+				stack = append(stack, &frame{key: child, children: utils.MapKeys(g.vertices[child].children)})
+			}
 		}
 	}
 	return nil
@@ -325,7 +325,32 @@ func Test_detectCycle(t *testing.T) {
 	graph := exampleGraph()
 	graph.addEdge("B", "D")
 	err := graph.checkCycle()
-	assert.Error(t, err, "dependency cycle detected: D -> C -> B")
+	assert.Error(t, err, "dependency cycle detected: B -> D -> C -> B")
+}
+
+func Test_detectSelfCycle(t *testing.T) {
+	graph := exampleGraph()
+	graph.addEdge("F", "F")
+	err := graph.checkCycle()
+	assert.Error(t, err, "dependency cycle detected: F -> F")
+}
+
+func Test_detectCycleLargeGraph(t *testing.T) {
+	graph := &graph[types.ServiceConfig]{
+		vertices: map[string]*vertex[types.ServiceConfig]{},
+	}
+	const size = 10000
+	for i := 0; i < size; i++ {
+		graph.addVertex(fmt.Sprint(i), types.ServiceConfig{})
+	}
+	for i := 1; i < size; i++ {
+		graph.addEdge(fmt.Sprint(i-1), fmt.Sprint(i))
+	}
+	assert.NilError(t, graph.checkCycle())
+
+	graph.addEdge(fmt.Sprint(size-1), "0")
+	err := graph.checkCycle()
+	assert.ErrorContains(t, err, "dependency cycle detected: 0 -> 1 -> ")
 }
 
 func TestWith_RootNodesAndUp(t *testing.T) {
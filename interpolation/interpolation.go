@@ -20,6 +20,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/compose-spec/compose-go/v2/template"
 	"github.com/compose-spec/compose-go/v2/tree"
@@ -33,6 +35,20 @@ type Options struct {
 	TypeCastMapping map[tree.Path]Cast
 	// Substitution function to use
 	Substitute func(string, template.Mapping) (string, error)
+	// ErrorOnMissing makes Interpolate fail with a MissingVariablesError listing all variables which are not set
+	// and have no default value, rather than replacing them by a blank string. Substitute is then ignored, as
+	// template.SubstituteWithOptions is used to detect those variables
+	ErrorOnMissing bool
+}
+
+// MissingVariablesError is returned by Interpolate when ErrorOnMissing is set and some variables are not set
+type MissingVariablesError struct {
+	// Variables are the sorted names of the missing variables
+	Variables []string
+}
+
+func (e MissingVariablesError) Error() string {
+	return fmt.Sprintf("variables are not set and have no default value: %s", strings.Join(e.Variables, ", "))
 }
 
 // LookupValue is a function which maps from variable names to values.
@@ -55,6 +71,15 @@ func Interpolate(config map[string]interface{}, opts Options) (map[string]interf
 	if opts.Substitute == nil {
 		opts.Substitute = template.Substitute
 	}
+	missing := map[string]struct{}{}
+	if opts.ErrorOnMissing {
+		onMissing := template.WithMissingVariableFunction(func(name string) {
+			missing[name] = struct{}{}
+		})
+		opts.Substitute = func(value string, mapping template.Mapping) (string, error) {
+			return template.SubstituteWithOptions(value, mapping, onMissing)
+		}
+	}
 
 	out := map[string]interface{}{}
 
@@ -66,6 +91,14 @@ func Interpolate(config map[string]interface{}, opts Options) (map[string]interf
 		out[key] = interpolatedValue
 	}
 
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return out, MissingVariablesError{Variables: names}
+	}
 	return out, nil
 }
 
@@ -88,12 +121,28 @@ func recursiveInterpolate(value interface{}, path tree.Path, opts Options) (inte
 
 	case map[string]interface{}:
 		out := map[string]interface{}{}
+		// origins records the declared key for each interpolated one, to detect keys colliding after interpolation
+		origins := map[string]string{}
 		for key, elem := range value {
-			interpolatedElem, err := recursiveInterpolate(elem, path.Next(key), opts)
+			interpolatedKey := key
+			if interpolatesKeys(path) && strings.Contains(key, "$") {
+				k, err := opts.Substitute(key, template.Mapping(opts.LookupValue))
+				if err != nil {
+					return nil, newPathError(path.Next(key), err)
+				}
+				interpolatedKey = k
+			}
+			if origin, ok := origins[interpolatedKey]; ok {
+				keys := []string{origin, key}
+				sort.Strings(keys)
+				return nil, fmt.Errorf("%s: keys %q and %q both resolve to %q after interpolation", path, keys[0], keys[1], interpolatedKey)
+			}
+			origins[interpolatedKey] = key
+			interpolatedElem, err := recursiveInterpolate(elem, path.Next(interpolatedKey), opts)
 			if err != nil {
 				return nil, err
 			}
-			out[key] = interpolatedElem
+			out[interpolatedKey] = interpolatedElem
 		}
 		return out, nil
 
@@ -113,6 +162,21 @@ func recursiveInterpolate(value interface{}, path tree.Path, opts Options) (inte
 	}
 }
 
+// interpolatesKeys returns true if keys of the mapping at path are interpolated, which only applies to labels,
+// environment and extensions. Other keys, like resource names, are left unchanged
+func interpolatesKeys(path tree.Path) bool {
+	switch path.Last() {
+	case "labels", "environment":
+		return true
+	}
+	for _, part := range path.Parts() {
+		if strings.HasPrefix(part, "x-") {
+			return true
+		}
+	}
+	return false
+}
+
 func newPathError(path tree.Path, err error) error {
 	var ite *template.InvalidTemplateError
 	switch {
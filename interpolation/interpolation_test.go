@@ -17,10 +17,12 @@
 package interpolation
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
 
+	"github.com/compose-spec/compose-go/v2/template"
 	"github.com/compose-spec/compose-go/v2/tree"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
@@ -67,6 +69,29 @@ func TestInterpolate(t *testing.T) {
 	assert.Check(t, is.DeepEqual(expected, result))
 }
 
+func TestInterpolateWithEscapeCharacter(t *testing.T) {
+	services := map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"image":   "example:${USER}",
+			"command": "echo `$FOO `${USER} $FOO",
+		},
+	}
+	expected := map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"image":   "example:jenny",
+			"command": "echo $FOO ${USER} bar",
+		},
+	}
+	result, err := Interpolate(services, Options{
+		LookupValue: defaultMapping,
+		Substitute: func(s string, mapping template.Mapping) (string, error) {
+			return template.SubstituteWithOptions(s, mapping, template.WithEscapeCharacter('`'))
+		},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(expected, result))
+}
+
 func TestInvalidInterpolation(t *testing.T) {
 	services := map[string]interface{}{
 		"servicea": map[string]interface{}{
@@ -258,3 +283,86 @@ func TestPathMatches(t *testing.T) {
 		assert.Check(t, is.Equal(testcase.expected, testcase.path.Matches(testcase.pattern)))
 	}
 }
+
+func TestInterpolateErrorOnMissing(t *testing.T) {
+	config := map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"image":       "example:${TAG}",
+			"environment": []interface{}{"USER=$USER", "HOST=${HOST:-localhost}", "PORT=${PORT-}", "ESCAPED=$$ESCAPED"},
+			"command":     "${CMD} ${ARGS} ${TAG}",
+		},
+	}
+	_, err := Interpolate(config, Options{LookupValue: defaultMapping, ErrorOnMissing: true})
+	var missing MissingVariablesError
+	assert.Check(t, errors.As(err, &missing))
+	assert.DeepEqual(t, missing.Variables, []string{"ARGS", "CMD", "TAG"})
+	assert.Error(t, err, "variables are not set and have no default value: ARGS, CMD, TAG")
+
+	delete(config["servicea"].(map[string]interface{}), "command")
+	config["servicea"].(map[string]interface{})["image"] = "example:${TAG:?tag is required}"
+	_, err = Interpolate(config, Options{LookupValue: defaultMapping, ErrorOnMissing: true})
+	assert.ErrorContains(t, err, "tag is required")
+
+	config["servicea"].(map[string]interface{})["image"] = "example:${USER}"
+	result, err := Interpolate(config, Options{LookupValue: defaultMapping, ErrorOnMissing: true})
+	assert.NilError(t, err)
+	assert.Equal(t, result["servicea"].(map[string]interface{})["image"], "example:jenny")
+
+	// variables nested in a default value are reported when the default value is used
+	config["servicea"].(map[string]interface{})["image"] = "example:${TAG:-${FALLBACK}}"
+	_, err = Interpolate(config, Options{LookupValue: defaultMapping, ErrorOnMissing: true})
+	assert.Error(t, err, "variables are not set and have no default value: FALLBACK")
+}
+
+func TestInterpolateKeys(t *testing.T) {
+	services := map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"com.${FOO}.user": "$USER",
+				"com.$$escaped":   "1",
+			},
+			"x-custom": map[string]interface{}{
+				"${USER}": map[string]interface{}{"count": "${count}"},
+			},
+		},
+	}
+	expected := map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"com.bar.user": "jenny",
+				"com.$escaped": "1",
+			},
+			"x-custom": map[string]interface{}{
+				"jenny": map[string]interface{}{"count": "5"},
+			},
+		},
+	}
+	result, err := Interpolate(services, Options{LookupValue: defaultMapping})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(expected, result))
+
+	_, err = Interpolate(map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"com.${FOO}": "1",
+				"com.bar":    "2",
+			},
+		},
+	}, Options{LookupValue: defaultMapping})
+	assert.Error(t, err, `servicea.labels: keys "com.${FOO}" and "com.bar" both resolve to "com.bar" after interpolation`)
+
+	// keys of other mappings, like resource names, are not interpolated
+	result, err = Interpolate(map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"networks":    map[string]interface{}{"${FOO}": nil},
+			"environment": map[string]interface{}{"${FOO}": "$$escaped"},
+		},
+	}, Options{LookupValue: defaultMapping})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(map[string]interface{}{
+		"servicea": map[string]interface{}{
+			"networks":    map[string]interface{}{"${FOO}": nil},
+			"environment": map[string]interface{}{"bar": "$escaped"},
+		},
+	}, result))
+}
@@ -19,10 +19,13 @@ package loader
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/consts"
+	"github.com/compose-spec/compose-go/v2/dotenv"
+	interp "github.com/compose-spec/compose-go/v2/interpolation"
 	"github.com/compose-spec/compose-go/v2/override"
 	"github.com/compose-spec/compose-go/v2/types"
 )
@@ -36,8 +39,9 @@ func ApplyExtends(ctx context.Context, dict map[string]any, opts *Options, track
 	if !ok {
 		return fmt.Errorf("services must be a mapping")
 	}
+	depths := map[string]int{}
 	for name := range services {
-		merged, err := applyServiceExtends(ctx, name, services, opts, tracker, post...)
+		merged, _, err := applyServiceExtends(ctx, name, services, depths, opts, tracker, post...)
 		if err != nil {
 			return err
 		}
@@ -47,18 +51,20 @@ func ApplyExtends(ctx context.Context, dict map[string]any, opts *Options, track
 	return nil
 }
 
-func applyServiceExtends(ctx context.Context, name string, services map[string]any, opts *Options, tracker *cycleTracker, post ...PostProcessor) (any, error) {
+// applyServiceExtends resolves `extends` for service name, and returns the merged service definition with the length
+// of the extends chain. depths records the extends chain length for already resolved services
+func applyServiceExtends(ctx context.Context, name string, services map[string]any, depths map[string]int, opts *Options, tracker *cycleTracker, post ...PostProcessor) (any, int, error) {
 	s := services[name]
 	if s == nil {
-		return nil, nil
+		return nil, 0, nil
 	}
 	service, ok := s.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("services.%s must be a mapping", name)
+		return nil, 0, fmt.Errorf("services.%s must be a mapping", name)
 	}
 	extends, ok := service["extends"]
 	if !ok {
-		return s, nil
+		return s, depths[name], nil
 	}
 	filename := ctx.Value(consts.ComposeFileKey{}).(string)
 	var (
@@ -77,59 +83,77 @@ func applyServiceExtends(ctx context.Context, name string, services map[string]a
 	}
 
 	var base any
+	baseDepths := depths
+	source := filename
+	// file declaring the extending service, to track the extends chain
+	declaringFile := filename
 	if file != nil {
 		filename = file.(string)
-		services, err = getExtendsBaseFromFile(ctx, ref, filename, opts, tracker)
+		services, source, err = getExtendsBaseFromFile(ctx, ref, filename, opts, tracker)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
+		// base service may extend another service declared in the same file
+		ctx = context.WithValue(ctx, consts.ComposeFileKey{}, source)
+		baseDepths = map[string]int{}
 	} else {
 		_, ok := services[ref]
 		if !ok {
-			return nil, fmt.Errorf("cannot extend service %q in %s: service not found", name, filename)
+			return nil, 0, fmt.Errorf("cannot extend service %q in %s: service not found", name, filename)
 		}
 	}
 
-	tracker, err = tracker.Add(filename, name)
+	tracker, err = tracker.Add(declaringFile, name)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// recursively apply `extends`
-	base, err = applyServiceExtends(ctx, ref, services, opts, tracker, post...)
+	base, depth, err := applyServiceExtends(ctx, ref, services, baseDepths, opts, tracker, post...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	depth++
+	if opts.MaxExtendsDepth > 0 && depth > opts.MaxExtendsDepth {
+		return nil, 0, fmt.Errorf("cannot extend service %q: extends chain exceeds maximum depth of %d", name, opts.MaxExtendsDepth)
 	}
 
 	if base == nil {
-		return service, nil
+		return service, depth, nil
 	}
-	source := deepClone(base).(map[string]any)
+	baseService := deepClone(base).(map[string]any)
 
-	err = validateExtendSource(source, ref)
+	err = validateExtendSource(baseService, ref)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	for _, processor := range post {
 		processor.Apply(map[string]any{
 			"services": map[string]any{
-				name: source,
+				name: baseService,
 			},
 		})
 	}
-	merged, err := override.ExtendService(source, service)
+	merged, err := override.ExtendService(baseService, service)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	delete(merged, "extends")
+	if opts.RecordExtendsSource {
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+		merged[types.ExtendsSourceExtension] = source
+	}
 	services[name] = merged
-	return merged, nil
+	depths[name] = depth
+	return merged, depth, nil
 }
 
 // validateExtendSource check the source for `extends` doesn't refer to another container/service
 func validateExtendSource(source map[string]any, ref string) error {
-	forbidden := []string{"links", "volumes_from", "depends_on"}
+	forbidden := []string{"links", "volumes_from"}
 	for _, key := range forbidden {
 		if _, ok := source[key]; ok {
 			return fmt.Errorf("service %q can't be used with `extends` as it declare `%s`", ref, key)
@@ -151,14 +175,15 @@ func validateExtendSource(source map[string]any, ref string) error {
 	return nil
 }
 
-func getExtendsBaseFromFile(ctx context.Context, name string, path string, opts *Options, ct *cycleTracker) (map[string]any, error) {
+// getExtendsBaseFromFile loads services from the compose file at path, and returns them with the local path to this file
+func getExtendsBaseFromFile(ctx context.Context, name string, path string, opts *Options, ct *cycleTracker) (map[string]any, string, error) {
 	for _, loader := range opts.ResourceLoaders {
 		if !loader.Accept(path) {
 			continue
 		}
 		local, err := loader.Load(ctx, path)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		localdir := filepath.Dir(local)
 		relworkingdir := loader.Dir(path)
@@ -175,6 +200,16 @@ func getExtendsBaseFromFile(ctx context.Context, name string, path string, opts
 		extendsOpts.SkipExtends = true    // we manage extends recursively based on raw service definition
 		extendsOpts.SkipValidation = true // we validate the merge result
 		extendsOpts.SkipDefaultValues = true
+		// base service attributes don't match paths in the extending model
+		extendsOpts.TrackSourcePositions = false
+		extendsOpts.sourceMap = nil
+		if opts.Interpolate != nil {
+			interpolate, err := withBaseEnvFile(*opts.Interpolate, localdir)
+			if err != nil {
+				return nil, "", err
+			}
+			extendsOpts.Interpolate = interpolate
+		}
 		source, err := loadYamlModel(ctx, types.ConfigDetails{
 			WorkingDir: relworkingdir,
 			ConfigFiles: []types.ConfigFile{
@@ -182,16 +217,41 @@ func getExtendsBaseFromFile(ctx context.Context, name string, path string, opts
 			},
 		}, extendsOpts, ct, nil)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		services := source["services"].(map[string]any)
 		_, ok := services[name]
 		if !ok {
-			return nil, fmt.Errorf("cannot extend service %q in %s: service not found", name, path)
+			return nil, "", fmt.Errorf("cannot extend service %q in %s: service not found", name, path)
+		}
+		return services, local, nil
+	}
+	return nil, "", fmt.Errorf("cannot read %s", path)
+}
+
+// withBaseEnvFile returns interpolation options to load a base file for extends, so that variables are resolved by
+// the `.env` file next to the base file, if any. The base file's own environment takes precedence, the extending
+// project environment only being used for variables the base `.env` file doesn't define.
+func withBaseEnvFile(interpolate interp.Options, dir string) (*interp.Options, error) {
+	envFile := filepath.Join(dir, ".env")
+	if s, err := os.Stat(envFile); err != nil || s.IsDir() {
+		return &interpolate, nil
+	}
+	lookup := interpolate.LookupValue
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	env, err := dotenv.ReadWithLookup(dotenv.LookupFn(lookup), envFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", envFile, err)
+	}
+	interpolate.LookupValue = func(key string) (string, bool) {
+		if v, ok := env[key]; ok {
+			return v, true
 		}
-		return services, nil
+		return lookup(key)
 	}
-	return nil, fmt.Errorf("cannot read %s", path)
+	return &interpolate, nil
 }
 
 func deepClone(value any) any {
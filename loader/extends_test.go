@@ -18,10 +18,12 @@ package loader
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
+	interp "github.com/compose-spec/compose-go/v2/interpolation"
 	"github.com/compose-spec/compose-go/v2/types"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
@@ -129,6 +131,47 @@ services:
 	assert.Equal(t, p.Services["test"].Ulimits["nproc"].Single, 65535)
 }
 
+func TestExtendsDependsOn(t *testing.T) {
+	yaml := `
+name: test-extends-depends-on
+services:
+  base:
+    image: app
+    depends_on: [db, cache]
+  app:
+    extends: base
+    depends_on:
+      cache:
+        condition: service_healthy
+      queue:
+        condition: service_started
+  db:
+    image: db
+  cache:
+    image: cache
+  queue:
+    image: queue
+`
+	abs, err := filepath.Abs(".")
+	assert.NilError(t, err)
+
+	p, err := LoadWithContext(context.Background(), types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{
+				Content:  []byte(yaml),
+				Filename: "(inline)",
+			},
+		},
+		WorkingDir: abs,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["app"].DependsOn, types.DependsOnConfig{
+		"db":    {Condition: types.ServiceConditionStarted, Required: true},
+		"cache": {Condition: types.ServiceConditionHealthy, Required: true},
+		"queue": {Condition: types.ServiceConditionStarted, Required: true},
+	})
+}
+
 func TestExtendsRelativePath(t *testing.T) {
 	yaml := `
 name: test-extends-port
@@ -326,20 +369,6 @@ services:
 `,
 			wantErr: "service \"foo\" can't be used with `extends` as it declare `volumes_from`",
 		},
-		{
-			name: "depends_on",
-			yaml: `
-name: test-extends_with_depends_on
-services:
-  foo:
-    depends_on:
-      - zot
-  bar:
-    extends:
-      service: foo
-`,
-			wantErr: "service \"foo\" can't be used with `extends` as it declare `depends_on`",
-		},
 		{
 			name: "shared ipc",
 			yaml: `
@@ -460,3 +489,166 @@ services:
 	assert.NilError(t, err)
 	assert.Equal(t, extendsCount, 2)
 }
+
+func TestLoadExtendsMaxDepth(t *testing.T) {
+	yaml := `
+name: test-extends-depth
+services:
+  a:
+    extends: b
+  b:
+    extends: c
+  c:
+    extends: d
+  d:
+    image: foo
+`
+	_, err := loadYAML(yaml)
+	assert.NilError(t, err)
+
+	_, err = LoadWithContext(context.Background(), buildConfigDetails(yaml, nil), func(options *Options) {
+		options.MaxExtendsDepth = 2
+	})
+	assert.ErrorContains(t, err, `cannot extend service "a": extends chain exceeds maximum depth of 2`)
+}
+
+func TestLoadExtendsCycle(t *testing.T) {
+	_, err := loadYAML(`
+name: test-extends-cycle
+services:
+  a:
+    extends: a
+`)
+	assert.Error(t, err, `Circular reference:
+  a in filename0.yml
+  extends a in filename0.yml`)
+}
+
+func TestLoadExtendsRecordSource(t *testing.T) {
+	yaml := `
+name: test-extends-source
+services:
+  test1:
+    extends:
+      file: testdata/extends/base.yaml
+      service: base
+  test2:
+    extends:
+      file: testdata/extends/base.yaml
+      service: another
+  test3:
+    extends: test1
+  test4:
+    image: test
+`
+	abs, err := filepath.Abs(".")
+	assert.NilError(t, err)
+	configDetails := types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{
+				Content:  []byte(yaml),
+				Filename: filepath.Join(abs, "compose.yaml"),
+			},
+		},
+		WorkingDir: abs,
+	}
+
+	p, err := LoadWithContext(context.Background(), configDetails, func(options *Options) {
+		options.RecordExtendsSource = true
+	})
+	assert.NilError(t, err)
+	base := filepath.Join(abs, "testdata", "extends", "base.yaml")
+	assert.Equal(t, p.Services["test1"].Extensions[types.ExtendsSourceExtension], base)
+	assert.Equal(t, p.Services["test2"].Extensions[types.ExtendsSourceExtension], base)
+	assert.Equal(t, p.Services["test3"].Extensions[types.ExtendsSourceExtension], filepath.Join(abs, "compose.yaml"))
+	assert.Check(t, p.Services["test4"].Extensions == nil)
+	assert.Equal(t, p.Services["test1"].Image, "base")
+
+	p, err = LoadWithContext(context.Background(), configDetails)
+	assert.NilError(t, err)
+	_, ok := p.Services["test1"].Extensions[types.ExtendsSourceExtension]
+	assert.Check(t, !ok)
+}
+
+func TestExtendsBuild(t *testing.T) {
+	p, err := loadYAML(`
+name: extends-build
+services:
+  dev:
+    extends:
+      file: testdata/extends-build/base/compose.yaml
+      service: app
+    build:
+      dockerfile: Dockerfile.dev
+      target: dev
+      args:
+        DEBUG: "true"
+  short:
+    extends:
+      file: testdata/extends-build/base/compose.yaml
+      service: app
+    build: ./local
+`)
+	assert.NilError(t, err)
+	abs, err := filepath.Abs(".")
+	assert.NilError(t, err)
+
+	dev := p.Services["dev"].Build
+	// inherited context is relative to the base compose file
+	assert.Equal(t, dev.Context, filepath.Join(abs, "testdata", "extends-build", "base", "app"))
+	assert.Equal(t, dev.Dockerfile, "Dockerfile.dev")
+	assert.Equal(t, dev.Target, "dev")
+	assert.DeepEqual(t, dev.Args, types.NewMappingWithEquals([]string{"VERSION=1.0", "DEBUG=true"}))
+	assert.DeepEqual(t, dev.Labels, types.Labels{"com.example.base": "true"})
+
+	short := p.Services["short"].Build
+	// local context is relative to the extending compose file
+	assert.Equal(t, short.Context, filepath.Join(abs, "local"))
+	assert.Equal(t, short.Dockerfile, "Dockerfile")
+	assert.Equal(t, short.Target, "prod")
+	assert.DeepEqual(t, short.Args, types.NewMappingWithEquals([]string{"VERSION=1.0", "DEBUG=false"}))
+}
+
+func TestExtendsBaseEnvFile(t *testing.T) {
+	yaml := `
+name: test-extends-env
+services:
+  test:
+    extends:
+      file: testdata/extends/withenv/base.yaml
+      service: base
+`
+	p, err := Load(buildConfigDetails(yaml, map[string]string{"BASE_SOURCE": "project"}))
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["test"].Image, "base-image:from-base-env")
+	assert.DeepEqual(t, p.Services["test"].Environment, types.MappingWithEquals{"SOURCE": strPtr("project")})
+
+	// base .env file takes precedence over extending project environment
+	p, err = Load(buildConfigDetails(yaml, map[string]string{"BASE_TAG": "from-project"}))
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["test"].Image, "base-image:from-base-env")
+	assert.DeepEqual(t, p.Services["test"].Environment, types.MappingWithEquals{"SOURCE": strPtr("base")})
+
+	// interpolation options without lookup fall back to os environment
+	t.Setenv("BASE_SOURCE", "os")
+	p, err = Load(buildConfigDetails(yaml, nil), func(options *Options) {
+		options.Interpolate = &interp.Options{}
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["test"].Image, "base-image:from-base-env")
+	assert.DeepEqual(t, p.Services["test"].Environment, types.MappingWithEquals{"SOURCE": strPtr("os")})
+}
+
+func TestLoadExtendsCycleAcrossFiles(t *testing.T) {
+	abs, err := filepath.Abs(filepath.Join("testdata", "extends", "cycle"))
+	assert.NilError(t, err)
+
+	_, err = Load(types.ConfigDetails{
+		WorkingDir:  abs,
+		ConfigFiles: []types.ConfigFile{{Filename: filepath.Join(abs, "first.yaml")}},
+	}, withProjectName("test-extends-cycle", true))
+	assert.ErrorContains(t, err, fmt.Sprintf(`Circular reference:
+  first in %[1]s
+  extends second in %[2]s
+  extends first in %[1]s`, filepath.Join(abs, "first.yaml"), filepath.Join(abs, "second.yaml")))
+}
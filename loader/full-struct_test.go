@@ -1079,7 +1079,9 @@ func fullExampleJSON(workingDir, homeDir string) string {
     },
     "config4": {
       "name": "foo",
-      "file": "%s"
+      "file": "%s",
+      "x-bar": "baz",
+      "x-foo": "bar"
     }
   },
   "name": "full_example_project_name",
@@ -1091,7 +1093,9 @@ func fullExampleJSON(workingDir, homeDir string) string {
     "other-external-network": {
       "name": "my-cool-network",
       "ipam": {},
-      "external": true
+      "external": true,
+      "x-bar": "baz",
+      "x-foo": "bar"
     },
     "other-network": {
       "driver": "overlay",
@@ -1142,7 +1146,9 @@ func fullExampleJSON(workingDir, homeDir string) string {
     },
     "secret4": {
       "name": "bar",
-      "environment": "BAR"
+      "environment": "BAR",
+      "x-bar": "baz",
+      "x-foo": "bar"
     },
     "secret5": {
       "file": "/abs/secret_data"
@@ -1647,7 +1653,9 @@ func fullExampleJSON(workingDir, homeDir string) string {
           }
         }
       ],
-      "working_dir": "/code"
+      "working_dir": "/code",
+      "x-bar": "baz",
+      "x-foo": "bar"
     }
   },
   "volumes": {
@@ -1664,7 +1672,9 @@ func fullExampleJSON(workingDir, homeDir string) string {
     },
     "external-volume3": {
       "name": "this-is-volume3",
-      "external": true
+      "external": true,
+      "x-bar": "baz",
+      "x-foo": "bar"
     },
     "other-external-volume": {
       "name": "my-cool-volume",
@@ -26,6 +26,7 @@ import (
 
 	"github.com/compose-spec/compose-go/v2/dotenv"
 	interp "github.com/compose-spec/compose-go/v2/interpolation"
+	"github.com/compose-spec/compose-go/v2/template"
 	"github.com/compose-spec/compose-go/v2/types"
 )
 
@@ -47,6 +48,58 @@ func loadIncludeConfig(source any) ([]types.IncludeConfig, error) {
 	return requires, err
 }
 
+// checkIncludePaths reports include paths relying on an undefined variable, which interpolation
+// would otherwise silently replace by a blank string
+func checkIncludePaths(source any, lookup interp.LookupValue) error {
+	includes, ok := source.([]any)
+	if !ok {
+		return nil
+	}
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var paths []string
+	for _, include := range includes {
+		switch v := include.(type) {
+		case string:
+			paths = append(paths, v)
+		case map[string]any:
+			switch p := v["path"].(type) {
+			case string:
+				paths = append(paths, p)
+			case []any:
+				for _, e := range p {
+					if s, ok := e.(string); ok {
+						paths = append(paths, s)
+					}
+				}
+			}
+		}
+	}
+	for _, p := range paths {
+		var missing string
+		mapping := func(key string) (string, bool) {
+			value, ok := lookup(key)
+			if !ok {
+				missing = key
+			}
+			return value, ok
+		}
+		replace := func(substring string, mapping template.Mapping, cfg *template.Config) (string, error) {
+			missing = ""
+			value, applied, err := template.DefaultReplacementAppliedFunc(substring, mapping, cfg)
+			if err == nil && !applied {
+				return "", fmt.Errorf("include path %q references undefined variable %s", p, missing)
+			}
+			return value, err
+		}
+		if _, err := template.SubstituteWithOptions(p, mapping, template.WithReplacementFunction(replace), template.WithoutLogging); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ApplyInclude(ctx context.Context, configDetails types.ConfigDetails, model map[string]any, options *Options, included []string) error {
 	includeConfig, err := loadIncludeConfig(model["include"])
 	if err != nil {
@@ -101,10 +154,19 @@ func ApplyInclude(ctx context.Context, configDetails types.ConfigDetails, model
 
 		if len(r.EnvFile) == 0 {
 			f := filepath.Join(r.ProjectDirectory, ".env")
+			if !filepath.IsAbs(f) {
+				f = filepath.Join(configDetails.WorkingDir, f)
+			}
 			if s, err := os.Stat(f); err == nil && !s.IsDir() {
 				r.EnvFile = types.StringList{f}
 			}
 		}
+		for i, f := range r.EnvFile {
+			// env_file is relative to the including project working directory
+			if !filepath.IsAbs(f) {
+				r.EnvFile[i] = filepath.Join(configDetails.WorkingDir, f)
+			}
+		}
 
 		envFromFile, err := dotenv.GetEnvFromFile(configDetails.Environment, r.EnvFile)
 		if err != nil {
@@ -120,6 +182,7 @@ func ApplyInclude(ctx context.Context, configDetails types.ConfigDetails, model
 			Substitute:      options.Interpolate.Substitute,
 			LookupValue:     config.LookupEnv,
 			TypeCastMapping: options.Interpolate.TypeCastMapping,
+			ErrorOnMissing:  options.Interpolate.ErrorOnMissing,
 		}
 		imported, err := loadYamlModel(ctx, config, loadOptions, &cycleTracker{}, included)
 		if err != nil {
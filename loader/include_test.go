@@ -89,3 +89,52 @@ func TestIncludeRelative(t *testing.T) {
 	assert.Equal(t, included.Build.Context, ".")
 	assert.Equal(t, included.Volumes[0].Source, ".")
 }
+
+func TestIncludeLongForm(t *testing.T) {
+	wd, err := filepath.Abs(filepath.Join("testdata", "include-long-form"))
+	assert.NilError(t, err)
+	p, err := LoadWithContext(context.Background(), types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{
+				Filename: filepath.Join(wd, "compose.yaml"),
+			},
+		},
+		WorkingDir: wd,
+	})
+	assert.NilError(t, err)
+	component := p.Services["component"]
+	// interpolated using the include env_file
+	assert.Equal(t, component.Image, "component:1.2.3")
+	// paths are resolved relative to project_directory
+	assert.Equal(t, component.Build.Context, filepath.Join(wd, "app"))
+	// multiple paths are merged in order
+	assert.DeepEqual(t, component.Environment, types.NewMappingWithEquals([]string{"LEVEL=override"}))
+}
+
+func TestIncludeInterpolatedPath(t *testing.T) {
+	p, err := Load(buildConfigDetails(`
+name: 'test-include-interpolated'
+
+include:
+  - ${STACK_DIR}/compose-include.yaml
+  - path: ${OTHER_DIR:-./testdata/subdir}/compose-test-extends-imported.yaml
+    env_file: ./testdata/subdir/extra.env
+`, map[string]string{"STACK_DIR": "./testdata", "SOURCE": "override"}), func(options *Options) {
+		options.SkipNormalization = true
+		options.ResolvePaths = true
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["bar"].Image, "bar")
+	assert.Equal(t, p.Services["imported"].ContainerName, "override")
+
+	_, err = Load(buildConfigDetails(`
+name: 'test-include-interpolated'
+
+include:
+  - ${STACK_DIR}/compose-include.yaml
+`, map[string]string{}), func(options *Options) {
+		options.SkipNormalization = true
+		options.ResolvePaths = true
+	})
+	assert.ErrorContains(t, err, `include path "${STACK_DIR}/compose-include.yaml" references undefined variable STACK_DIR`)
+}
@@ -27,47 +27,49 @@ import (
 )
 
 var interpolateTypeCastMapping = map[tree.Path]interp.Cast{
-	servicePath("configs", tree.PathMatchList, "mode"):             toInt,
-	servicePath("cpu_count"):                                       toInt64,
-	servicePath("cpu_percent"):                                     toFloat,
-	servicePath("cpu_period"):                                      toInt64,
-	servicePath("cpu_quota"):                                       toInt64,
-	servicePath("cpu_rt_period"):                                   toInt64,
-	servicePath("cpu_rt_runtime"):                                  toInt64,
-	servicePath("cpus"):                                            toFloat32,
-	servicePath("cpu_shares"):                                      toInt64,
-	servicePath("init"):                                            toBoolean,
-	servicePath("deploy", "replicas"):                              toInt,
-	servicePath("deploy", "update_config", "parallelism"):          toInt,
-	servicePath("deploy", "update_config", "max_failure_ratio"):    toFloat,
-	servicePath("deploy", "rollback_config", "parallelism"):        toInt,
-	servicePath("deploy", "rollback_config", "max_failure_ratio"):  toFloat,
-	servicePath("deploy", "restart_policy", "max_attempts"):        toInt,
-	servicePath("deploy", "placement", "max_replicas_per_node"):    toInt,
-	servicePath("healthcheck", "retries"):                          toInt,
-	servicePath("healthcheck", "disable"):                          toBoolean,
-	servicePath("oom_kill_disable"):                                toBoolean,
-	servicePath("oom_score_adj"):                                   toInt64,
-	servicePath("pids_limit"):                                      toInt64,
-	servicePath("ports", tree.PathMatchList, "target"):             toInt,
-	servicePath("privileged"):                                      toBoolean,
-	servicePath("read_only"):                                       toBoolean,
-	servicePath("scale"):                                           toInt,
-	servicePath("secrets", tree.PathMatchList, "mode"):             toInt,
-	servicePath("stdin_open"):                                      toBoolean,
-	servicePath("tty"):                                             toBoolean,
-	servicePath("ulimits", tree.PathMatchAll):                      toInt,
-	servicePath("ulimits", tree.PathMatchAll, "hard"):              toInt,
-	servicePath("ulimits", tree.PathMatchAll, "soft"):              toInt,
-	servicePath("volumes", tree.PathMatchList, "read_only"):        toBoolean,
-	servicePath("volumes", tree.PathMatchList, "volume", "nocopy"): toBoolean,
-	iPath("networks", tree.PathMatchAll, "external"):               toBoolean,
-	iPath("networks", tree.PathMatchAll, "internal"):               toBoolean,
-	iPath("networks", tree.PathMatchAll, "attachable"):             toBoolean,
-	iPath("networks", tree.PathMatchAll, "enable_ipv6"):            toBoolean,
-	iPath("volumes", tree.PathMatchAll, "external"):                toBoolean,
-	iPath("secrets", tree.PathMatchAll, "external"):                toBoolean,
-	iPath("configs", tree.PathMatchAll, "external"):                toBoolean,
+	servicePath("configs", tree.PathMatchList, "mode"): toFileMode,
+	servicePath("cpu_count"):                           toInt64,
+	servicePath("cpu_percent"):                         toFloat,
+	servicePath("cpu_period"):                          toInt64,
+	servicePath("cpu_quota"):                           toInt64,
+	servicePath("cpu_rt_period"):                       toInt64,
+	servicePath("cpu_rt_runtime"):                      toInt64,
+	servicePath("cpus"):                                toFloat32,
+	servicePath("cpu_shares"):                          toInt64,
+	servicePath("init"):                                toBoolean,
+	servicePath("deploy", "replicas"):                  toInt,
+	servicePath("deploy", "resources", "reservations", "devices", tree.PathMatchList, "count"): toDeviceCount,
+	servicePath("deploy", "update_config", "parallelism"):                                      toInt,
+	servicePath("deploy", "update_config", "max_failure_ratio"):                                toFloat,
+	servicePath("deploy", "rollback_config", "parallelism"):                                    toInt,
+	servicePath("deploy", "rollback_config", "max_failure_ratio"):                              toFloat,
+	servicePath("deploy", "restart_policy", "max_attempts"):                                    toInt,
+	servicePath("deploy", "placement", "max_replicas_per_node"):                                toInt,
+	servicePath("gpus", tree.PathMatchList, "count"):                                           toDeviceCount,
+	servicePath("healthcheck", "retries"):                                                      toInt,
+	servicePath("healthcheck", "disable"):                                                      toBoolean,
+	servicePath("oom_kill_disable"):                                                            toBoolean,
+	servicePath("oom_score_adj"):                                                               toInt64,
+	servicePath("pids_limit"):                                                                  toInt64,
+	servicePath("ports", tree.PathMatchList, "target"):                                         toInt,
+	servicePath("privileged"):                                                                  toBoolean,
+	servicePath("read_only"):                                                                   toBoolean,
+	servicePath("scale"):                                                                       toInt,
+	servicePath("secrets", tree.PathMatchList, "mode"):                                         toFileMode,
+	servicePath("stdin_open"):                                                                  toBoolean,
+	servicePath("tty"):                                                                         toBoolean,
+	servicePath("ulimits", tree.PathMatchAll):                                                  toInt,
+	servicePath("ulimits", tree.PathMatchAll, "hard"):                                          toInt,
+	servicePath("ulimits", tree.PathMatchAll, "soft"):                                          toInt,
+	servicePath("volumes", tree.PathMatchList, "read_only"):                                    toBoolean,
+	servicePath("volumes", tree.PathMatchList, "volume", "nocopy"):                             toBoolean,
+	iPath("networks", tree.PathMatchAll, "external"):                                           toBoolean,
+	iPath("networks", tree.PathMatchAll, "internal"):                                           toBoolean,
+	iPath("networks", tree.PathMatchAll, "attachable"):                                         toBoolean,
+	iPath("networks", tree.PathMatchAll, "enable_ipv6"):                                        toBoolean,
+	iPath("volumes", tree.PathMatchAll, "external"):                                            toBoolean,
+	iPath("secrets", tree.PathMatchAll, "external"):                                            toBoolean,
+	iPath("configs", tree.PathMatchAll, "external"):                                            toBoolean,
 }
 
 func iPath(parts ...string) tree.Path {
@@ -82,6 +84,12 @@ func toInt(value string) (interface{}, error) {
 	return strconv.Atoi(value)
 }
 
+// toFileMode parses a file mode, which is octal when prefixed by `0` or `0o`, like YAML integers
+func toFileMode(value string) (interface{}, error) {
+	mode, err := strconv.ParseInt(value, 0, 32)
+	return int(mode), err
+}
+
 func toInt64(value string) (interface{}, error) {
 	return strconv.ParseInt(value, 10, 64)
 }
@@ -98,6 +106,18 @@ func toFloat32(value string) (interface{}, error) {
 	return float32(f), nil
 }
 
+// toDeviceCount keeps the special value `all` for a device count, which otherwise must be an integer
+func toDeviceCount(value string) (interface{}, error) {
+	if strings.ToLower(value) == "all" {
+		return "all", nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q, the only value allowed is 'all' or a number", value)
+	}
+	return i, nil
+}
+
 // should match http://yaml.org/type/bool.html
 func toBoolean(value string) (interface{}, error) {
 	switch strings.ToLower(value) {
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -44,10 +45,19 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultMaxExtendsDepth is the default limit for a chain of services extending each other
+const DefaultMaxExtendsDepth = 10
+
 // Options supported by Load
 type Options struct {
 	// Skip schema validation
 	SkipValidation bool
+	// SkipSchemaValidation only skips validation against the compose JSON schema, while semantic validation of
+	// the model and the consistency check still apply
+	SkipSchemaValidation bool
+	// SkipSchemaValidationSections lists sections to be excluded from schema validation, as dot-separated paths
+	// like `networks` or `services.web`
+	SkipSchemaValidationSections []string
 	// Skip interpolation
 	SkipInterpolation bool
 	// Skip normalization
@@ -56,8 +66,17 @@ type Options struct {
 	ResolvePaths bool
 	// Convert Windows path
 	ConvertWindowsPaths bool
+	// RestrictBindMountsTo rejects bind mounts, and local volumes bound to a host device, which source,
+	// once resolved, lies outside of this directory
+	RestrictBindMountsTo string
 	// Skip consistency check
 	SkipConsistencyCheck bool
+	// WarnUnknownUlimits logs a warning for service ulimits which are not supported by the Linux kernel, as they
+	// would be rejected by the engine. Other platforms may support other limits, so this is opt-in
+	WarnUnknownUlimits bool
+	// WarnRelativeWorkingDir logs a warning for service working_dir which are not absolute paths, as those are
+	// resolved relative to the image WORKDIR
+	WarnRelativeWorkingDir bool
 	// Skip extends
 	SkipExtends bool
 	// SkipInclude will ignore `include` and only load model from file(s) set by ConfigDetails
@@ -66,16 +85,30 @@ type Options struct {
 	SkipResolveEnvironment bool
 	// SkipDefaultValues will ignore missing required attributes
 	SkipDefaultValues bool
+	// MaxExtendsDepth limits the length of a chain of services extending each other. Zero means no limit
+	MaxExtendsDepth int
+	// RecordExtendsSource records the absolute path of the file each service was extended from in the service
+	// extensions, under types.ExtendsSourceExtension
+	RecordExtendsSource bool
+	// TrackSourcePositions records the position of attributes in compose files while parsing, so that errors can
+	// be reported as a SourceError pointing to the offending attribute
+	TrackSourcePositions bool
 	// Interpolation options
 	Interpolate *interp.Options
 	// Discard 'env_file' entries after resolving to 'environment' section
 	discardEnvFiles bool
 	// Set project projectName
 	projectName string
+	// sourceMap records attributes position when TrackSourcePositions is enabled
+	sourceMap SourceMap
 	// Indicates when the projectName was imperatively set or guessed from path
 	projectNameImperativelySet bool
-	// Profiles set profiles to enable
+	// Profiles set profiles to enable. Services gated behind other profiles are disabled while loading, see
+	// types.Project.AllProfiles to discover the profiles declared by a project
 	Profiles []string
+	// ResourceProfiles drops networks, volumes, secrets and configs gated behind inactive profiles using the
+	// types.ProfilesExtension extension
+	ResourceProfiles bool
 	// ResourceLoaders manages support for remote resources
 	ResourceLoaders []ResourceLoader
 	// KnownExtensions manages x-* attribute we know and the corresponding go structs
@@ -149,25 +182,38 @@ func (l localResourceLoader) Dir(path string) string {
 
 func (o *Options) clone() *Options {
 	return &Options{
-		SkipValidation:             o.SkipValidation,
-		SkipInterpolation:          o.SkipInterpolation,
-		SkipNormalization:          o.SkipNormalization,
-		ResolvePaths:               o.ResolvePaths,
-		ConvertWindowsPaths:        o.ConvertWindowsPaths,
-		SkipConsistencyCheck:       o.SkipConsistencyCheck,
-		SkipExtends:                o.SkipExtends,
-		SkipInclude:                o.SkipInclude,
-		Interpolate:                o.Interpolate,
-		discardEnvFiles:            o.discardEnvFiles,
-		projectName:                o.projectName,
-		projectNameImperativelySet: o.projectNameImperativelySet,
-		Profiles:                   o.Profiles,
-		ResourceLoaders:            o.ResourceLoaders,
-		KnownExtensions:            o.KnownExtensions,
-		Listeners:                  o.Listeners,
-	}
-}
-
+		SkipValidation:               o.SkipValidation,
+		SkipSchemaValidation:         o.SkipSchemaValidation,
+		SkipSchemaValidationSections: o.SkipSchemaValidationSections,
+		SkipInterpolation:            o.SkipInterpolation,
+		SkipNormalization:            o.SkipNormalization,
+		ResolvePaths:                 o.ResolvePaths,
+		ConvertWindowsPaths:          o.ConvertWindowsPaths,
+		RestrictBindMountsTo:         o.RestrictBindMountsTo,
+		SkipConsistencyCheck:         o.SkipConsistencyCheck,
+		WarnUnknownUlimits:           o.WarnUnknownUlimits,
+		WarnRelativeWorkingDir:       o.WarnRelativeWorkingDir,
+		SkipExtends:                  o.SkipExtends,
+		SkipInclude:                  o.SkipInclude,
+		MaxExtendsDepth:              o.MaxExtendsDepth,
+		RecordExtendsSource:          o.RecordExtendsSource,
+		TrackSourcePositions:         o.TrackSourcePositions,
+		sourceMap:                    o.sourceMap,
+		Interpolate:                  o.Interpolate,
+		discardEnvFiles:              o.discardEnvFiles,
+		projectName:                  o.projectName,
+		projectNameImperativelySet:   o.projectNameImperativelySet,
+		Profiles:                     o.Profiles,
+		ResourceProfiles:             o.ResourceProfiles,
+		ResourceLoaders:              o.ResourceLoaders,
+		KnownExtensions:              o.KnownExtensions,
+		Listeners:                    o.Listeners,
+	}
+}
+
+// SetProjectName sets the project name. An imperatively set name takes precedence over the top-level `name` declared
+// by the compose files, while a name which is not, typically derived from the working directory, is only used as
+// a fallback when compose files don't declare one
 func (o *Options) SetProjectName(name string, imperativelySet bool) {
 	o.projectName = name
 	o.projectNameImperativelySet = imperativelySet
@@ -228,6 +274,19 @@ func WithSkipValidation(opts *Options) {
 	opts.SkipValidation = true
 }
 
+// WithSkipSchemaValidation sets the Options to skip validation against the compose JSON schema only
+func WithSkipSchemaValidation(opts *Options) {
+	opts.SkipSchemaValidation = true
+}
+
+// WithErrorOnMissingVariables sets the Options to fail interpolation when variables are not set and have no
+// default value, rather than replacing them by a blank string
+func WithErrorOnMissingVariables(opts *Options) {
+	if opts.Interpolate != nil {
+		opts.Interpolate.ErrorOnMissing = true
+	}
+}
+
 // WithProfiles sets profiles to be activated
 func WithProfiles(profiles []string) func(*Options) {
 	return func(opts *Options) {
@@ -297,7 +356,8 @@ func LoadWithContext(ctx context.Context, configDetails types.ConfigDetails, opt
 			LookupValue:     configDetails.LookupEnv,
 			TypeCastMapping: interpolateTypeCastMapping,
 		},
-		ResolvePaths: true,
+		ResolvePaths:    true,
+		MaxExtendsDepth: DefaultMaxExtendsDepth,
 	}
 
 	for _, op := range options {
@@ -326,6 +386,9 @@ func loadYamlModel(ctx context.Context, config types.ConfigDetails, opts *Option
 		dict = map[string]interface{}{}
 		err  error
 	)
+	if opts.TrackSourcePositions && opts.sourceMap == nil {
+		opts.sourceMap = SourceMap{}
+	}
 	for _, file := range config.ConfigFiles {
 		fctx := context.WithValue(ctx, consts.ComposeFileKey{}, file.Filename)
 		if file.Content == nil && file.Config == nil {
@@ -347,6 +410,11 @@ func loadYamlModel(ctx context.Context, config types.ConfigDetails, opts *Option
 			}
 
 			if opts.Interpolate != nil && !opts.SkipInterpolation {
+				if !opts.SkipInclude {
+					if err := checkIncludePaths(cfg["include"], opts.Interpolate.LookupValue); err != nil {
+						return err
+					}
+				}
 				cfg, err = interp.Interpolate(cfg, *opts.Interpolate)
 				if err != nil {
 					return err
@@ -386,8 +454,15 @@ func loadYamlModel(ctx context.Context, config types.ConfigDetails, opts *Option
 				return err
 			}
 
-			if !opts.SkipValidation {
-				if err := schema.Validate(dict); err != nil {
+			if !opts.SkipValidation && !opts.SkipSchemaValidation {
+				if err := schema.Validate(withoutSections(dict, opts.SkipSchemaValidationSections)); err != nil {
+					var fieldErr interface{ Field() string }
+					if errors.As(err, &fieldErr) {
+						path := tree.Path(fieldErr.Field())
+						if pos, ok := opts.sourceMap.Lookup(path); ok {
+							return &SourceError{Path: path, Position: pos, Err: err}
+						}
+					}
 					return fmt.Errorf("validating %s: %w", file.Filename, err)
 				}
 			}
@@ -400,7 +475,12 @@ func loadYamlModel(ctx context.Context, config types.ConfigDetails, opts *Option
 			decoder := yaml.NewDecoder(r)
 			for {
 				var raw interface{}
-				processor := &ResetProcessor{target: &raw}
+				processor := &ResetProcessor{
+					target:     &raw,
+					filename:   file.Filename,
+					sourceMap:  opts.sourceMap,
+					workingDir: config.WorkingDir,
+				}
 				err := decoder.Decode(processor)
 				if err != nil && errors.Is(err, io.EOF) {
 					break
@@ -457,6 +537,33 @@ func loadYamlModel(ctx context.Context, config types.ConfigDetails, opts *Option
 	return dict, nil
 }
 
+// withoutSections returns a shallow copy of dict without the sections set as dot-separated paths, so those can be
+// excluded from schema validation without altering the model
+func withoutSections(dict map[string]any, sections []string) map[string]any {
+	if len(sections) == 0 {
+		return dict
+	}
+	copied := maps.Clone(dict)
+	for _, section := range sections {
+		m := copied
+		parts := strings.Split(section, ".")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := m[part].(map[string]any)
+			if !ok {
+				m = nil
+				break
+			}
+			next = maps.Clone(next)
+			m[part] = next
+			m = next
+		}
+		if m != nil {
+			delete(m, parts[len(parts)-1])
+		}
+	}
+	return copied
+}
+
 func load(ctx context.Context, configDetails types.ConfigDetails, opts *Options, loaded []string) (*types.Project, error) {
 	mainFile := configDetails.ConfigFiles[0].Filename
 	for _, f := range loaded {
@@ -513,6 +620,12 @@ func load(ctx context.Context, configDetails types.ConfigDetails, opts *Options,
 		}
 	}
 
+	if opts.RestrictBindMountsTo != "" {
+		if err := checkBindMounts(project, opts.RestrictBindMountsTo); err != nil {
+			return nil, err
+		}
+	}
+
 	if !opts.SkipConsistencyCheck {
 		err := checkConsistency(project)
 		if err != nil {
@@ -520,10 +633,28 @@ func load(ctx context.Context, configDetails types.ConfigDetails, opts *Options,
 		}
 	}
 
+	if opts.WarnUnknownUlimits {
+		checkUlimitNames(project)
+	}
+
+	if opts.WarnRelativeWorkingDir {
+		checkWorkingDirs(project)
+	}
+
 	if project, err = project.WithProfiles(opts.Profiles); err != nil {
 		return nil, err
 	}
 
+	if !opts.SkipConsistencyCheck {
+		if err := checkContainerNames(project); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ResourceProfiles {
+		project = project.WithResourceProfiles(opts.Profiles)
+	}
+
 	if !opts.SkipResolveEnvironment {
 		project, err = project.WithServicesEnvironmentResolved(opts.discardEnvFiles)
 		if err != nil {
@@ -604,9 +735,12 @@ func projectName(details types.ConfigDetails, opts *Options) error {
 		}
 		pjNameFromConfigFile = interpolated["name"].(string)
 	}
-	pjNameFromConfigFile = NormalizeProjectName(pjNameFromConfigFile)
-	if pjNameFromConfigFile != "" {
-		opts.projectName = pjNameFromConfigFile
+	normalized := NormalizeProjectName(pjNameFromConfigFile)
+	if pjNameFromConfigFile != "" && normalized == "" {
+		return InvalidProjectNameErr(pjNameFromConfigFile)
+	}
+	if normalized != "" {
+		opts.projectName = normalized
 	}
 	return nil
 }
@@ -28,6 +28,7 @@ import (
 
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 
@@ -148,7 +149,7 @@ var sampleDict = map[string]interface{}{
 	},
 }
 
-var samplePortsConfig = []types.ServicePortConfig{
+var samplePortsConfig = types.Ports{
 	{
 		Mode:      "ingress",
 		Target:    8080,
@@ -968,6 +969,52 @@ services:
 	assert.DeepEqual(t, configWithoutEnvFiles.Services["web"].Environment, expectedEnvironmentMap)
 }
 
+func TestLoadEnvFileRequired(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "local.env")
+	err := os.WriteFile(envFile, []byte("FOO=foo_from_local"), 0o600)
+	assert.NilError(t, err)
+	missing := filepath.Join(dir, "missing.env")
+
+	p, err := Load(buildConfigDetails(fmt.Sprintf(`
+name: test
+services:
+  web:
+    image: nginx
+    env_file:
+      - %s
+      - path: %s
+        required: false
+`, envFile, missing), nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["web"].EnvFiles, []types.EnvFile{
+		{Path: envFile, Required: true},
+		{Path: missing, Required: false},
+	})
+	assert.DeepEqual(t, p.Services["web"].Environment, types.MappingWithEquals{"FOO": strPtr("foo_from_local")})
+
+	// short syntax is required
+	_, err = Load(buildConfigDetails(fmt.Sprintf(`
+name: test
+services:
+  web:
+    image: nginx
+    env_file: %s
+`, missing), nil))
+	assert.ErrorContains(t, err, fmt.Sprintf("env file %s not found", missing))
+
+	_, err = Load(buildConfigDetails(fmt.Sprintf(`
+name: test
+services:
+  web:
+    image: nginx
+    env_file:
+      - path: %s
+        required: true
+`, missing), nil))
+	assert.ErrorContains(t, err, fmt.Sprintf("env file %s not found", missing))
+}
+
 func TestDecodeErrors(t *testing.T) {
 	dict := "name: test\nservices:\n  web:\n    image: nginx\n\tbuild: ."
 
@@ -2099,6 +2146,63 @@ services:
 	assert.ErrorContains(t, err, `invalid value "some_string", the only value allowed is 'all' or a number`)
 }
 
+func TestServiceDeviceRequests(t *testing.T) {
+	p, err := loadYAML(`
+name: service-device-requests
+services:
+  trainer:
+    image: trainer
+    deploy:
+      resources:
+        reservations:
+          devices:
+            - driver: nvidia
+              capabilities: [gpu, compute]
+              device_ids: ["0", "3"]
+              options:
+                virtualization: false
+  all:
+    image: trainer
+    gpus: all
+  some:
+    image: trainer
+    gpus:
+      - driver: nvidia
+        count: ${GPU_COUNT:-2}
+`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["trainer"].Deploy.Resources.Reservations.Devices, []types.DeviceRequest{
+		{
+			Driver:       "nvidia",
+			Capabilities: []string{"gpu", "compute"},
+			IDs:          []string{"0", "3"},
+			Options:      types.Mapping{"virtualization": "false"},
+		},
+	})
+	assert.DeepEqual(t, p.Services["all"].Gpus, []types.DeviceRequest{
+		{Capabilities: []string{"gpu"}, Count: types.AllDevices},
+	})
+	assert.DeepEqual(t, p.Services["some"].Gpus, []types.DeviceRequest{
+		{Driver: "nvidia", Count: 2},
+	})
+
+	b, err := p.MarshalYAML()
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(string(b), "count: all"))
+	b, err = p.MarshalJSON()
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(string(b), `"count":"all"`))
+
+	_, err = loadYAML(`
+name: service-device-requests
+services:
+  trainer:
+    image: trainer
+    gpus: some
+`)
+	assert.ErrorContains(t, err, `services.trainer.gpus must be one of the following: "all"`)
+}
+
 func TestServicePullPolicy(t *testing.T) {
 	actual, err := loadYAML(`
 name: service-pull-policy
@@ -2357,6 +2461,68 @@ volumes:
 	assert.Check(t, filepath.IsAbs(path))
 }
 
+func TestLoadVolumeMountOptions(t *testing.T) {
+	workingDir, err := os.Getwd()
+	assert.NilError(t, err)
+	project, err := loadYAML(`
+name: load-volume-mount-options
+services:
+  web:
+    image: web
+    volumes:
+      - ./data:/data:ro,z
+      - ./logs:/logs:rshared
+      - type: bind
+        source: ./config
+        target: /config
+        bind:
+          propagation: rslave
+      - type: tmpfs
+        target: /tmp
+        tmpfs:
+          size: 10m
+          mode: 0755
+`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, project.Services["web"].Volumes, []types.ServiceVolumeConfig{
+		{
+			Type:     types.VolumeTypeBind,
+			Source:   filepath.Join(workingDir, "data"),
+			Target:   "/data",
+			ReadOnly: true,
+			Bind: &types.ServiceVolumeBind{
+				SELinux:        types.SELinuxShared,
+				CreateHostPath: true,
+			},
+		},
+		{
+			Type:   types.VolumeTypeBind,
+			Source: filepath.Join(workingDir, "logs"),
+			Target: "/logs",
+			Bind: &types.ServiceVolumeBind{
+				Propagation:    types.PropagationRShared,
+				CreateHostPath: true,
+			},
+		},
+		{
+			Type:   types.VolumeTypeBind,
+			Source: filepath.Join(workingDir, "config"),
+			Target: "/config",
+			Bind: &types.ServiceVolumeBind{
+				Propagation: types.PropagationRSlave,
+			},
+		},
+		{
+			Type:   types.VolumeTypeTmpfs,
+			Target: "/tmp",
+			Tmpfs: &types.ServiceVolumeTmpfs{
+				Size: types.UnitBytes(10 * 1024 * 1024),
+				Mode: 0o755,
+			},
+		},
+	})
+}
+
 func TestLoadServiceExtension(t *testing.T) {
 	dict := `
 name: test
@@ -2608,7 +2774,7 @@ func TestLoadDependsOnCycle(t *testing.T) {
 			},
 		},
 	})
-	assert.Error(t, err, "dependency cycle detected: service2 -> service3 -> service1", err)
+	assert.Error(t, err, "dependency cycle detected: service1 -> service2 -> service3 -> service1", err)
 }
 
 func TestLoadWithDependsOn(t *testing.T) {
@@ -2735,8 +2901,9 @@ func TestLoadWithNestedResources(t *testing.T) {
 name: test-nested-resources
 include:
   - remote:nested/compose.yaml
+  - remote:nested/compose-relative.yaml
 `, nil)
-	_, err := LoadWithContext(context.Background(), config, func(options *Options) {
+	p, err := LoadWithContext(context.Background(), config, func(options *Options) {
 		options.SkipConsistencyCheck = true
 		options.SkipNormalization = true
 		options.ResolvePaths = true
@@ -2745,6 +2912,10 @@ include:
 		}
 	})
 	assert.NilError(t, err)
+	assert.Equal(t, p.Services["foo"].Image, "bar")
+	// extends in a remote resource resolves relative files from the local copy of the resource
+	assert.Equal(t, p.Services["relative"].Image, "bar")
+	assert.DeepEqual(t, p.Services["relative"].Environment, types.MappingWithEquals{"FROM": strPtr("relative")})
 }
 
 func TestLoadWithResourcesCycle(t *testing.T) {
@@ -3108,3 +3279,792 @@ services:
 	assert.NilError(t, err)
 	assert.Equal(t, p.Name, "test-with-empty-file")
 }
+
+func TestLoadLoggingOptions(t *testing.T) {
+	b, err := os.ReadFile("testdata/compose-logging-options.yaml")
+	assert.NilError(t, err)
+
+	p, err := loadYAML(string(b))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["test"].Logging, &types.LoggingConfig{
+		Driver: "json-file",
+		Options: types.Options{
+			"max-file":        "3",
+			"max-size":        "10m",
+			"compress":        "true",
+			"buffer-ratio":    "0.5",
+			"max-buffer-size": "1000000",
+			"mode":            "",
+		},
+	})
+}
+
+func TestLoadDeployResources(t *testing.T) {
+	p, err := loadYAML(`
+name: load-deploy-resources
+services:
+  test:
+    image: foo
+    deploy:
+      resources:
+        limits:
+          cpus: "1.5"
+          memory: 1Gi
+        reservations:
+          cpus: 0.50
+          memory: 512M
+  other:
+    image: foo
+    deploy:
+      resources:
+        limits:
+          cpus: 2
+          memory: 1GiB
+        reservations:
+          memory: 1g
+`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["test"].Deploy.Resources, types.Resources{
+		Limits: &types.Resource{
+			NanoCPUs:    "1.5",
+			MemoryBytes: 1024 * 1024 * 1024,
+		},
+		Reservations: &types.Resource{
+			NanoCPUs:    "0.5",
+			MemoryBytes: 512 * 1024 * 1024,
+		},
+	})
+	assert.DeepEqual(t, p.Services["other"].Deploy.Resources, types.Resources{
+		Limits: &types.Resource{
+			NanoCPUs:    "2",
+			MemoryBytes: 1024 * 1024 * 1024,
+		},
+		Reservations: &types.Resource{
+			MemoryBytes: 1024 * 1024 * 1024,
+		},
+	})
+
+	cpus, err := p.Services["test"].Deploy.Resources.Reservations.CPUs()
+	assert.NilError(t, err)
+	assert.Equal(t, cpus, float32(0.5))
+
+	_, err = Load(buildConfigDetails(`
+name: load-deploy-resources
+services:
+  test:
+    image: foo
+    deploy:
+      resources:
+        limits:
+          cpus: one
+`, nil))
+	assert.Error(t, err, `services.test.deploy.resources.limits.cpus: invalid cpus value "one": invalid compose project`)
+
+	_, err = loadYAML(`
+name: load-deploy-resources
+services:
+  test:
+    image: foo
+    deploy:
+      resources:
+        reservations:
+          memory: 1Gx
+`)
+	assert.ErrorContains(t, err, `'services[test].deploy.resources.reservations.memory': invalid suffix`)
+}
+
+func TestLoadBuildExtensions(t *testing.T) {
+	b, err := os.ReadFile("testdata/compose-build-extensions.yaml")
+	assert.NilError(t, err)
+
+	expected := types.Extensions{
+		"x-bake": map[string]any{
+			"tags":      []any{"example/app:latest", "example/app:1.0"},
+			"platforms": []any{"linux/amd64"},
+		},
+	}
+	p, err := loadYAML(string(b))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["app"].Build.Extensions, expected)
+
+	yamlBytes, err := p.MarshalYAML()
+	assert.NilError(t, err)
+	p, err = loadYAML(string(yamlBytes))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["app"].Build.Extensions, expected)
+
+	jsonBytes, err := p.MarshalJSON()
+	assert.NilError(t, err)
+	p, err = loadYAML(string(jsonBytes))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["app"].Build.Extensions, expected)
+}
+
+func TestServiceSecretEffectiveTarget(t *testing.T) {
+	p, err := loadYAML(`
+name: secrets
+services:
+  foo:
+    image: foo
+    secrets:
+      - db_password
+      - source: api_key
+        target: /etc/api/key
+secrets:
+  db_password:
+    name: production_db_password
+    external: true
+  api_key:
+    external: true
+`)
+	assert.NilError(t, err)
+	secrets := p.Services["foo"].Secrets
+	// target is derived from the service reference, not the secret actual name
+	assert.Equal(t, secrets[0].EffectiveTarget(), "/run/secrets/db_password")
+	assert.Equal(t, secrets[1].EffectiveTarget(), "/etc/api/key")
+}
+
+func TestLoadExtraHostsHostGateway(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-extra-hosts.yaml"))
+	assert.NilError(t, err)
+	p, err := loadYAML(string(b))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["foo"].ExtraHosts, types.HostsList{
+		"host.docker.internal": []string{types.HostGateway},
+		"somehost":             []string{"162.242.195.82"},
+	})
+
+	_, err = Load(buildConfigDetails(`
+name: extra-hosts
+services:
+  foo:
+    image: alpine
+    extra_hosts:
+      - somehost=162.242.195
+`, nil))
+	assert.Error(t, err, `services.foo: invalid IP address "162.242.195" for host 'somehost' in extra_hosts: invalid compose project`)
+
+	p, err = Load(buildConfigDetails(`
+name: extra-hosts
+services:
+  foo:
+    image: alpine
+    extra_hosts:
+      - somehost=${IP}
+`, nil), func(options *Options) {
+		options.SkipInterpolation = true
+		options.SkipConsistencyCheck = true
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["foo"].ExtraHosts, types.HostsList{"somehost": []string{"${IP}"}})
+}
+
+func TestProjectVolumeUsage(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-volume-usage.yaml"))
+	assert.NilError(t, err)
+	p, err := loadYAML(string(b))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.VolumeUsage(), map[string][]types.VolumeMountSite{
+		"data": {
+			{Service: "backup", Target: "/backup/data"},
+			{Service: "web", Target: "/usr/share/nginx/html"},
+		},
+		"logs": {
+			{Service: "backup", Target: "/backup/logs"},
+		},
+	})
+}
+
+func TestLoadCommandWithVariableArgs(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-command-args.yaml"))
+	assert.NilError(t, err)
+	p, err := loadYAMLWithEnv(string(b), map[string]string{
+		"ARGS": `--verbose --name "hello world"`,
+	})
+	assert.NilError(t, err)
+	// shell-form command is split into argv after interpolation, using shell quoting rules
+	assert.DeepEqual(t, p.Services["shell-form"].Command, types.ShellCommand{"run", "--verbose", "--name", "hello world"})
+	// exec-form command is kept as-is
+	assert.DeepEqual(t, p.Services["exec-form"].Command, types.ShellCommand{"run", `--verbose --name "hello world"`})
+}
+
+func TestServiceNetworksListSyntax(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-networks-list.yaml"))
+	assert.NilError(t, err)
+	p, err := Load(buildConfigDetails(string(b), nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["web"].Networks, map[string]*types.ServiceNetworkConfig{
+		"back":  nil,
+		"front": nil,
+	})
+
+	out, err := yaml.Marshal(p.Services["web"])
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(string(out), `networks:
+    - back
+    - front
+`), string(out))
+
+	// networks declared with options keep the mapping syntax
+	out, err = yaml.Marshal(p.Services["db"])
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(string(out), `networks:
+    back:
+        aliases:
+            - database
+`), string(out))
+
+	yamlBytes, err := p.MarshalYAML()
+	assert.NilError(t, err)
+	reloaded, err := Load(buildConfigDetails(string(yamlBytes), nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reloaded.Services["web"].Networks, p.Services["web"].Networks)
+}
+
+func TestServicesAffectedByPath(t *testing.T) {
+	workingDir, err := filepath.Abs(filepath.Join("testdata", "affected-by-path"))
+	assert.NilError(t, err)
+	p, err := LoadWithContext(context.TODO(), types.ConfigDetails{
+		WorkingDir:  workingDir,
+		ConfigFiles: []types.ConfigFile{{Filename: filepath.Join(workingDir, "compose.yaml")}},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, p.ServicesAffectedByPath(filepath.Join(workingDir, "api", "Dockerfile")), []string{"api"})
+	assert.DeepEqual(t, p.ServicesAffectedByPath(filepath.Join("shared", "index.html")), []string{"web"})
+	assert.DeepEqual(t, p.ServicesAffectedByPath("app.env"), []string{"api", "worker"})
+	assert.Check(t, len(p.ServicesAffectedByPath(filepath.Join(workingDir, "api-v2", "main.go"))) == 0)
+	assert.Check(t, len(p.ServicesAffectedByPath(workingDir)) == 0)
+}
+
+func TestLoadResourceProfiles(t *testing.T) {
+	yaml := `
+name: resource-profiles
+services:
+  app:
+    image: app
+    networks: [backend]
+  debug:
+    image: debug
+    profiles: [debug]
+    volumes:
+      - traces:/traces
+networks:
+  backend:
+    x-profiles: [prod]
+  monitoring:
+    x-profiles: [monitoring]
+  default: {}
+volumes:
+  traces:
+    x-profiles: [debug]
+  metrics:
+    x-profiles: [monitoring]
+secrets:
+  token:
+    x-profiles: [debug]
+    file: ./token.txt
+configs:
+  settings:
+    file: ./settings.json
+`
+	load := func(profiles ...string) *types.Project {
+		p, err := Load(buildConfigDetails(yaml, nil), func(options *Options) {
+			options.SkipConsistencyCheck = true
+			options.ResourceProfiles = true
+			options.Profiles = profiles
+		})
+		assert.NilError(t, err)
+		return p
+	}
+
+	p := load()
+	// backend is kept as used by an active service
+	assert.DeepEqual(t, p.NetworkNames(), []string{"backend", "default"})
+	assert.Equal(t, len(p.VolumeNames()), 0)
+	assert.Equal(t, len(p.SecretNames()), 0)
+	assert.DeepEqual(t, p.ConfigNames(), []string{"settings"})
+
+	p = load("debug", "monitoring")
+	assert.DeepEqual(t, p.NetworkNames(), []string{"backend", "default", "monitoring"})
+	assert.DeepEqual(t, p.VolumeNames(), []string{"metrics", "traces"})
+	assert.DeepEqual(t, p.SecretNames(), []string{"token"})
+
+	p = load("*")
+	assert.DeepEqual(t, p.VolumeNames(), []string{"metrics", "traces"})
+
+	// without opt-in, resources are kept regardless of their profiles
+	p, err := Load(buildConfigDetails(yaml, nil), func(options *Options) {
+		options.SkipConsistencyCheck = true
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.VolumeNames(), []string{"metrics", "traces"})
+}
+
+func TestLoadRelativePathsRoundTrip(t *testing.T) {
+	workingDir, err := os.Getwd()
+	assert.NilError(t, err)
+	yaml := `
+name: relative-paths
+services:
+  web:
+    build:
+      context: ./web
+      additional_contexts:
+        assets: ./assets
+    env_file: ./web.env
+    volumes:
+      - ./data:/data
+      - /var/run/docker.sock:/var/run/docker.sock
+      - cache:/cache
+volumes:
+  cache: {}
+secrets:
+  token:
+    file: ./secrets/token.txt
+`
+	p, err := Load(buildConfigDetails(yaml, nil), func(options *Options) {
+		options.SkipConsistencyCheck = true
+		options.SkipResolveEnvironment = true
+	})
+	assert.NilError(t, err)
+	web := p.Services["web"]
+	assert.Equal(t, web.Build.Context, filepath.Join(workingDir, "web"))
+
+	rel := p.RelativePaths(workingDir)
+	web = rel.Services["web"]
+	assert.Equal(t, web.Build.Context, "./web")
+	assert.Equal(t, web.Build.AdditionalContexts["assets"], "./assets")
+	assert.Equal(t, web.EnvFiles[0].Path, "./web.env")
+	assert.Equal(t, web.Volumes[0].Source, "./data")
+	assert.Equal(t, web.Volumes[1].Source, "/var/run/docker.sock")
+	assert.Equal(t, web.Volumes[2].Source, "cache")
+	assert.Equal(t, rel.Secrets["token"].File, "./secrets/token.txt")
+	// original project is left unchanged
+	assert.Equal(t, p.Services["web"].Build.Context, filepath.Join(workingDir, "web"))
+
+	// portable model loads back to the same project
+	b, err := rel.MarshalYAML()
+	assert.NilError(t, err)
+	reloaded, err := Load(buildConfigDetails(string(b), nil), func(options *Options) {
+		options.SkipConsistencyCheck = true
+		options.SkipResolveEnvironment = true
+	})
+	assert.NilError(t, err)
+	assert.Check(t, reloaded.Equal(p))
+}
+
+func TestLoadErrorOnMissingVariables(t *testing.T) {
+	yaml := `
+name: missing-variables
+services:
+  web:
+    image: ${REGISTRY}/web:${TAG}
+    environment:
+      LEVEL: ${LEVEL:-info}
+      USER: ${USER}
+`
+	_, err := Load(buildConfigDetails(yaml, map[string]string{"USER": "me"}), WithErrorOnMissingVariables)
+	assert.Error(t, err, "variables are not set and have no default value: REGISTRY, TAG")
+
+	p, err := Load(buildConfigDetails(yaml, map[string]string{"USER": "me", "REGISTRY": "registry", "TAG": "1.0"}), WithErrorOnMissingVariables)
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["web"].Image, "registry/web:1.0")
+
+	p, err = Load(buildConfigDetails(yaml, map[string]string{"USER": "me"}))
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["web"].Image, "/web:")
+}
+
+func TestLoadFileReferenceMode(t *testing.T) {
+	yaml := `
+name: file-reference-mode
+services:
+  web:
+    image: web
+    configs:
+      - app
+      - source: app
+        target: /etc/app.conf
+        uid: "103"
+        gid: "103"
+        mode: 0440
+    secrets:
+      - token
+      - source: token
+        target: token.txt
+        mode: ${MODE}
+configs:
+  app:
+    file: ./app.conf
+secrets:
+  token:
+    file: ./token.txt
+`
+	p, err := loadYAMLWithEnv(yaml, map[string]string{"MODE": "0400"})
+	assert.NilError(t, err)
+	web := p.Services["web"]
+	assert.DeepEqual(t, web.Configs, []types.ServiceConfigObjConfig{
+		{Source: "app"},
+		{Source: "app", Target: "/etc/app.conf", UID: "103", GID: "103", Mode: uint32Ptr(0o440)},
+	})
+	assert.DeepEqual(t, web.Secrets, []types.ServiceSecretConfig{
+		{Source: "token", Target: "/run/secrets/token"},
+		{Source: "token", Target: "token.txt", Mode: uint32Ptr(0o400)},
+	})
+
+	_, err = loadYAMLWithEnv(yaml, map[string]string{"MODE": "011777"})
+	assert.ErrorContains(t, err, "file mode 011777 is out of range")
+}
+
+func TestLoadInvalidDuration(t *testing.T) {
+	yaml := `
+name: invalid-duration
+services:
+  web:
+    image: web
+    healthcheck:
+      interval: 1x
+`
+	_, err := loadYAML(yaml)
+	assert.ErrorContains(t, err, "services.web.healthcheck.interval Does not match format 'duration'")
+
+	_, err = Load(buildConfigDetails(yaml, nil), WithSkipValidation)
+	assert.ErrorContains(t, err, `'services[web].healthcheck.interval': time: unknown unit "x" in duration "1x"`)
+}
+
+func TestLoadSkipSchemaValidation(t *testing.T) {
+	yaml := `
+name: skip-schema-validation
+services:
+  web:
+    image: web
+  dev:
+    image: dev
+    unknown_attribute: true
+`
+	_, err := loadYAML(yaml)
+	assert.ErrorContains(t, err, "services.dev Additional property unknown_attribute is not allowed")
+
+	_, err = Load(buildConfigDetails(yaml, nil), func(options *Options) {
+		options.SkipSchemaValidationSections = []string{"services.web"}
+	})
+	assert.ErrorContains(t, err, "services.dev Additional property unknown_attribute is not allowed")
+
+	p, err := Load(buildConfigDetails(yaml, nil), func(options *Options) {
+		options.SkipSchemaValidationSections = []string{"services.dev"}
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["dev"].Image, "dev")
+
+	p, err = Load(buildConfigDetails(yaml, nil), WithSkipSchemaValidation)
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["web"].Image, "web")
+
+	_, err = Load(buildConfigDetails(`
+name: skip-schema-validation
+services:
+  web:
+    image: web
+    depends_on: [db]
+`, nil), WithSkipSchemaValidation)
+	assert.ErrorContains(t, err, `service "web" depends on undefined service db`)
+}
+
+func TestLoadNumericScalarsAsStrings(t *testing.T) {
+	p, err := loadYAML(`
+name: numeric-scalars
+services:
+  web:
+    image: web
+    expose: [8080, "8081", 9000-9001]
+`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["web"].Expose, types.StringOrNumberList{"8080", "8081", "9000-9001"})
+
+	p, err = Load(buildConfigDetails(`
+name: numeric-scalars
+services:
+  web:
+    image: web
+    dns: [1.5, 8]
+    entrypoint: [sleep, 10]
+    command: [0.5]
+`, nil), WithSkipValidation)
+	assert.NilError(t, err)
+	web := p.Services["web"]
+	assert.DeepEqual(t, web.DNS, types.StringList{"1.5", "8"})
+	assert.DeepEqual(t, web.Entrypoint, types.ShellCommand{"sleep", "10"})
+	assert.DeepEqual(t, web.Command, types.ShellCommand{"0.5"})
+}
+
+func TestLoadEmptyProfiles(t *testing.T) {
+	p, err := Load(buildConfigDetails(`
+name: empty-profiles
+services:
+  always:
+    image: always
+    profiles: []
+  debug:
+    image: debug
+    profiles: [debug, test]
+`, nil), WithProfiles([]string{"test"}))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.ServiceNames(), []string{"always", "debug"})
+
+	p, err = Load(buildConfigDetails(`
+name: empty-profiles
+services:
+  always:
+    image: always
+    profiles: []
+  debug:
+    image: debug
+    profiles: [debug, test]
+`, nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.ServiceNames(), []string{"always"})
+}
+
+func TestLoadAnnotations(t *testing.T) {
+	p, err := loadYAML(`
+name: annotations
+services:
+  list:
+    image: list
+    annotations:
+      - com.example.service=list
+      - com.example.empty=
+    build:
+      context: .
+      annotations:
+        - org.opencontainers.image.title=list
+        - org.opencontainers.image.source=https://example.com/repo
+  mapping:
+    image: mapping
+    annotations:
+      com.example.service: mapping
+    build:
+      context: .
+      annotations:
+        org.opencontainers.image.title: mapping
+`)
+	assert.NilError(t, err)
+
+	list := p.Services["list"]
+	assert.DeepEqual(t, list.Annotations, types.Mapping{"com.example.service": "list", "com.example.empty": ""})
+	assert.DeepEqual(t, list.Build.Annotations, types.Mapping{
+		"org.opencontainers.image.title":  "list",
+		"org.opencontainers.image.source": "https://example.com/repo",
+	})
+
+	mapping := p.Services["mapping"]
+	assert.DeepEqual(t, mapping.Annotations, types.Mapping{"com.example.service": "mapping"})
+	assert.DeepEqual(t, mapping.Build.Annotations, types.Mapping{"org.opencontainers.image.title": "mapping"})
+}
+
+func TestLoadProjectNamePrecedence(t *testing.T) {
+	yaml := `
+name: ${PROJECT:-from_file}
+services:
+  web:
+    image: web
+`
+	tests := []struct {
+		name     string
+		env      map[string]string
+		options  func(*Options)
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "imperatively set name takes precedence",
+			options:  withProjectName("explicit", true),
+			expected: "explicit",
+		},
+		{
+			name:     "top-level name is interpolated",
+			env:      map[string]string{"PROJECT": "interpolated"},
+			options:  withProjectName("directory", false),
+			expected: "interpolated",
+		},
+		{
+			name:     "top-level name takes precedence over derived name",
+			options:  withProjectName("directory", false),
+			expected: "from_file",
+		},
+		{
+			name:    "invalid top-level name",
+			env:     map[string]string{"PROJECT": "Invalid.Name"},
+			options: withProjectName("directory", false),
+			wantErr: `name Does not match pattern`,
+		},
+		{
+			name:    "top-level name without any valid char",
+			env:     map[string]string{"PROJECT": "__.."},
+			options: withProjectName("directory", false),
+			wantErr: `invalid project name "__.."`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Load(types.ConfigDetails{
+				ConfigFiles: []types.ConfigFile{{Filename: "compose.yaml", Content: []byte(yaml)}},
+				Environment: tt.env,
+			}, tt.options)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, p.Name, tt.expected)
+		})
+	}
+
+	// derived name is used when compose files don't declare one
+	p, err := Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{{Filename: "compose.yaml", Content: []byte("services:\n  web:\n    image: web\n")}},
+	}, withProjectName("directory", false))
+	assert.NilError(t, err)
+	assert.Equal(t, p.Name, "directory")
+}
+
+func TestLoadBuildKitAttributes(t *testing.T) {
+	p, err := loadYAML(`
+name: buildkit
+services:
+  web:
+    build:
+      context: .
+      cache_from:
+        - type=registry,ref=example.com/web:cache
+      cache_to:
+        - type=inline
+      network: host
+      ssh:
+        - default
+      secrets:
+        - token
+        - source: password
+          target: db_password
+secrets:
+  token:
+    environment: TOKEN
+  password:
+    file: ./password.txt
+`)
+	assert.NilError(t, err)
+	build := p.Services["web"].Build
+	assert.DeepEqual(t, build.CacheFrom, types.StringList{"type=registry,ref=example.com/web:cache"})
+	assert.DeepEqual(t, build.CacheTo, types.StringList{"type=inline"})
+	assert.Equal(t, build.Network, "host")
+	assert.DeepEqual(t, build.SSH, types.SSHConfig{{ID: "default"}})
+	assert.DeepEqual(t, build.Secrets, []types.ServiceSecretConfig{
+		{Source: "token"},
+		{Source: "password", Target: "db_password"},
+	})
+
+	_, err = Load(buildConfigDetails(`
+name: buildkit
+services:
+  web:
+    build:
+      context: .
+      secrets:
+        - token
+`, nil))
+	assert.Error(t, err, `service "web" refers to undefined build secret token: invalid compose project`)
+}
+
+func TestLoadInterpolatedLabelKeys(t *testing.T) {
+	p, err := loadYAMLWithEnv(`
+name: label-keys
+services:
+  web:
+    image: web
+    labels:
+      ${PREFIX}.version: "1"
+      ${PREFIX}.team: core
+`, map[string]string{"PREFIX": "com.example"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["web"].Labels, types.Labels{
+		"com.example.version": "1",
+		"com.example.team":    "core",
+	})
+}
+
+func TestLoadRestrictBindMounts(t *testing.T) {
+	workingDir, err := filepath.Abs(".")
+	assert.NilError(t, err)
+	yaml := `
+name: bind-mounts
+services:
+  web:
+    image: web
+    volumes:
+      - ./testdata:/data
+      - data:/var/lib/data
+volumes:
+  data: {}
+`
+	_, err = Load(buildConfigDetails(yaml, nil), func(options *Options) {
+		options.RestrictBindMountsTo = workingDir
+	})
+	assert.NilError(t, err)
+
+	_, err = Load(buildConfigDetails(`
+name: bind-mounts
+services:
+  web:
+    image: web
+    volumes:
+      - ../escape:/data
+`, nil), func(options *Options) {
+		options.RestrictBindMountsTo = workingDir
+	})
+	assert.Error(t, err, fmt.Sprintf("services.web: bind mount source %s is outside of %s: invalid compose project",
+		filepath.Join(filepath.Dir(workingDir), "escape"), workingDir))
+
+	_, err = Load(buildConfigDetails(`
+name: bind-mounts
+services:
+  web:
+    image: web
+    volumes:
+      - ~/secret:/data
+`, nil), func(options *Options) {
+		options.ResolvePaths = false
+		options.RestrictBindMountsTo = workingDir
+	})
+	assert.Error(t, err, fmt.Sprintf("services.web: bind mount source ~/secret is outside of %s: invalid compose project", workingDir))
+
+	_, err = Load(buildConfigDetails(`
+name: bind-mounts
+services:
+  web:
+    image: web
+    volumes:
+      - data:/data
+volumes:
+  data:
+    driver_opts:
+      type: none
+      o: bind
+      device: /etc
+`, nil), func(options *Options) {
+		options.RestrictBindMountsTo = workingDir
+	})
+	assert.Error(t, err, fmt.Sprintf("volumes.data: bind device /etc is outside of %s: invalid compose project", workingDir))
+}
+
+func TestLoadUnsetRestart(t *testing.T) {
+	p, err := loadYAML(`
+name: unset-restart
+services:
+  web:
+    image: web
+    restart: ${RESTART}
+`)
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["web"].Restart, "")
+}
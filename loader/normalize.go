@@ -17,9 +17,12 @@
 package loader
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/compose-spec/compose-go/v2/dotenv"
 	"github.com/compose-spec/compose-go/v2/errdefs"
 	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/sirupsen/logrus"
@@ -62,6 +65,10 @@ func Normalize(project *types.Project) error {
 		}
 		s.Environment = s.Environment.Resolve(fn)
 
+		if err := loadLabelFiles(name, &s, fn); err != nil {
+			return err
+		}
+
 		for _, link := range s.Links {
 			parts := strings.Split(link, ":")
 			if len(parts) == 2 {
@@ -121,6 +128,32 @@ func Normalize(project *types.Project) error {
 	return nil
 }
 
+// loadLabelFiles parses files set by label_file and merges them into service labels, inline labels taking precedence
+func loadLabelFiles(name string, s *types.ServiceConfig, lookupFn dotenv.LookupFn) error {
+	if len(s.LabelFiles) == 0 {
+		return nil
+	}
+	labels := types.Labels{}
+	for _, path := range s.LabelFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("services.%s: failed to load label file %s: %w", name, path, err)
+		}
+		fileLabels, err := dotenv.ParseWithLookup(bytes.NewBuffer(b), lookupFn)
+		if err != nil {
+			return fmt.Errorf("services.%s: failed to read label file %s: %w", name, path, err)
+		}
+		for k, v := range fileLabels {
+			labels[k] = v
+		}
+	}
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	s.Labels = labels
+	return nil
+}
+
 // IsServiceDependency check the relation set by ref refers to a service
 func IsServiceDependency(ref string) (string, bool) {
 	if strings.HasPrefix(
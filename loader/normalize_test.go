@@ -17,7 +17,9 @@
 package loader
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/compose-spec/compose-go/v2/types"
@@ -66,7 +68,7 @@ services:
         FOO: BAR
         ZOT: null
     networks:
-      default: null
+      - default
 networks:
   default:
     name: myProject_default
@@ -158,7 +160,7 @@ services:
         condition: service_started
         required: true
     networks:
-      default: null
+      - default
     volumes_from:
       - zot
       - container:xxx
@@ -174,7 +176,7 @@ services:
     network_mode: service:zot
   zot:
     networks:
-      default: null
+      - default
 networks:
   default:
     name: myProject_default
@@ -232,3 +234,30 @@ func TestImplicitContextPath(t *testing.T) {
 	assert.NilError(t, Normalize(project))
 	assert.Equal(t, ".", project.Services["test"].Build.Context)
 }
+
+func TestNormalizeLabelFiles(t *testing.T) {
+	wd, err := filepath.Abs(filepath.Join("testdata", "label-file"))
+	assert.NilError(t, err)
+	p, err := LoadWithContext(context.Background(), types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{
+				Filename: filepath.Join(wd, "compose.yaml"),
+			},
+		},
+		WorkingDir:  wd,
+		Environment: map[string]string{"TIER": "staging"},
+	})
+	assert.NilError(t, err)
+
+	web := p.Services["web"]
+	assert.DeepEqual(t, web.LabelFiles, types.StringList{filepath.Join(wd, "base.labels"), filepath.Join(wd, "prod.labels")})
+	assert.DeepEqual(t, web.Labels, types.Labels{
+		"com.example.team":  "backend",
+		"com.example.tier":  "prod",
+		"com.example.owner": "web-team",
+	})
+	assert.DeepEqual(t, p.Services["worker"].Labels, types.Labels{
+		"com.example.team": "backend",
+		"com.example.tier": "staging",
+	})
+}
@@ -18,6 +18,7 @@ package loader
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/compose-spec/compose-go/v2/types"
@@ -197,3 +198,26 @@ services:
 	assert.NilError(t, err)
 	assert.Equal(t, len(p.Services["test"].Volumes), 1)
 }
+
+func TestOverridePorts(t *testing.T) {
+	p, err := LoadWithContext(context.Background(), types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{Filename: filepath.Join("testdata", "ports-merge", "compose.yaml")},
+			{Filename: filepath.Join("testdata", "ports-merge", "compose.override.yaml")},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["web"].Ports, types.Ports{
+		{Mode: "ingress", Target: 80, Published: "8080", Protocol: "tcp"},
+		{Mode: "ingress", Target: 443, Published: "8443", Protocol: "tcp"},
+		{Target: 9090, Published: "9090"},
+	})
+
+	_, err = LoadWithContext(context.Background(), types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{Filename: filepath.Join("testdata", "ports-merge", "compose.yaml")},
+			{Filename: filepath.Join("testdata", "ports-merge", "compose.conflict.yaml")},
+		},
+	})
+	assert.Error(t, err, "services.web.ports: published port 8443/tcp is mapped to conflicting target ports 443 and 8443")
+}
@@ -0,0 +1,58 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package loader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// ApplyOverrides patches a loaded project with a partial compose model, as if it was declared by an additional
+// compose file. Overrides are deep-merged using the same rules as compose files merge, then the resulting model
+// is validated, so that an invalid attribute or a type mismatch is reported as a descriptive error.
+// The source project is not modified.
+func ApplyOverrides(ctx context.Context, project *types.Project, overrides map[string]any) (*types.Project, error) {
+	base, err := project.MarshalYAML()
+	if err != nil {
+		return nil, err
+	}
+
+	details := types.ConfigDetails{
+		WorkingDir: project.WorkingDir,
+		ConfigFiles: []types.ConfigFile{
+			{Filename: "project", Content: base},
+			{Filename: "overrides", Config: overrides},
+		},
+		Environment: project.Environment,
+	}
+
+	patched, err := LoadWithContext(ctx, details, func(options *Options) {
+		options.SetProjectName(project.Name, true)
+		// values from the loaded project are already interpolated, and have `$$` escapes removed
+		options.SkipInterpolation = true
+		options.Profiles = project.Profiles
+	})
+	if err != nil {
+		return nil, fmt.Errorf("applying overrides: %w", err)
+	}
+
+	patched.ComposeFiles = project.ComposeFiles
+	patched.DisabledServices = project.DisabledServices
+	return patched, nil
+}
@@ -0,0 +1,91 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package loader
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	project, err := loadYAML(`
+name: test
+services:
+  web:
+    image: nginx
+    environment:
+      FOO: foo
+    ports:
+      - 8080:80
+    deploy:
+      replicas: 1
+  db:
+    image: postgres
+    profiles: [debug]
+`)
+	assert.NilError(t, err)
+
+	patched, err := ApplyOverrides(context.Background(), project, map[string]any{
+		"services": map[string]any{
+			"web": map[string]any{
+				"environment": map[string]any{"BAR": "bar"},
+				"ports":       []any{"8443:443"},
+				"deploy":      map[string]any{"replicas": 3},
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	web := patched.Services["web"]
+	assert.Equal(t, *web.Deploy.Replicas, 3)
+	assert.Equal(t, *web.Environment["FOO"], "foo")
+	assert.Equal(t, *web.Environment["BAR"], "bar")
+	assert.Equal(t, len(web.Ports), 2)
+	assert.Equal(t, patched.Name, "test")
+	_, disabled := patched.DisabledServices["db"]
+	assert.Check(t, disabled)
+
+	// source project is left untouched
+	assert.Equal(t, *project.Services["web"].Deploy.Replicas, 1)
+	assert.Equal(t, len(project.Services["web"].Environment), 1)
+}
+
+func TestApplyOverridesInvalid(t *testing.T) {
+	project, err := loadYAML(`
+name: test
+services:
+  web:
+    image: nginx
+`)
+	assert.NilError(t, err)
+
+	_, err = ApplyOverrides(context.Background(), project, map[string]any{
+		"services": map[string]any{
+			"web": map[string]any{"replicas": 3},
+		},
+	})
+	assert.ErrorContains(t, err, "validating overrides: services.web Additional property replicas is not allowed")
+
+	_, err = ApplyOverrides(context.Background(), project, map[string]any{
+		"services": map[string]any{
+			"web": map[string]any{"deploy": map[string]any{"replicas": "three"}},
+		},
+	})
+	assert.ErrorContains(t, err, "services.web.deploy.replicas must be a integer")
+}
@@ -0,0 +1,120 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package loader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// ValidateForPlatform checks services attributes are supported by the target platform, for example `linux`,
+// `windows` or `linux/arm64`. This is advisory: unsupported attributes are reported as warnings, which
+// caller can decide to display or consider as an error.
+func ValidateForPlatform(project *types.Project, platform string) []string {
+	os, _, _ := strings.Cut(strings.ToLower(platform), "/")
+	var warnings []string
+	for _, name := range project.ServiceNames() {
+		s := project.Services[name]
+		for _, attr := range unsupportedAttributes(s, os) {
+			warnings = append(warnings, fmt.Sprintf("services.%s: %s is not supported on %s", name, attr, os))
+		}
+	}
+	return warnings
+}
+
+// unsupportedAttributes returns the service attributes set which are not supported by os
+func unsupportedAttributes(s types.ServiceConfig, os string) []string {
+	var attrs []string
+	check := func(attr string, set bool) {
+		if set {
+			attrs = append(attrs, attr)
+		}
+	}
+	switch os {
+	case "windows":
+		check("cap_add", len(s.CapAdd) > 0)
+		check("cap_drop", len(s.CapDrop) > 0)
+		check("cgroup_parent", s.CgroupParent != "")
+		check("devices", len(s.Devices) > 0)
+		check("ipc", s.Ipc != "")
+		check("oom_kill_disable", s.OomKillDisable)
+		check("pid", s.Pid != "")
+		check("privileged", s.Privileged)
+		check("security_opt", len(s.SecurityOpt) > 0)
+		check("sysctls", len(s.Sysctls) > 0)
+		check("tmpfs", len(s.Tmpfs) > 0)
+		check("userns_mode", s.UserNSMode != "")
+		for _, v := range s.Volumes {
+			if v.Type == types.VolumeTypeTmpfs {
+				check(fmt.Sprintf("tmpfs volume %s", v.Target), true)
+			}
+		}
+	case "linux":
+		check("credential_spec", s.CredentialSpec != nil)
+		check("isolation", s.Isolation != "" && s.Isolation != "default")
+	}
+	return attrs
+}
+
+// normalizePlatform returns the canonical form of a platform, so that equivalent platforms like `linux/aarch64` and
+// `linux/arm64/v8`, or `linux/arm` and `linux/arm/v7`, can be compared
+func normalizePlatform(platform string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(platform)), "/")
+	os := parts[0]
+	if os == "macos" {
+		os = "darwin"
+	}
+	if len(parts) == 1 {
+		return os
+	}
+	arch := parts[1]
+	variant := ""
+	if len(parts) > 2 {
+		variant = strings.Join(parts[2:], "/")
+	}
+	switch arch {
+	case "i386":
+		arch = "386"
+	case "x86_64", "x86-64", "amd64":
+		arch = "amd64"
+		if variant == "v1" {
+			variant = ""
+		}
+	case "aarch64", "arm64":
+		arch = "arm64"
+		if variant == "8" || variant == "v8" {
+			variant = ""
+		}
+	case "armhf":
+		arch, variant = "arm", "v7"
+	case "armel":
+		arch, variant = "arm", "v6"
+	case "arm":
+		switch variant {
+		case "", "7":
+			variant = "v7"
+		case "5", "6", "8":
+			variant = "v" + variant
+		}
+	}
+	if variant == "" {
+		return os + "/" + arch
+	}
+	return os + "/" + arch + "/" + variant
+}
@@ -18,7 +18,10 @@ package loader
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/compose-spec/compose-go/v2/tree"
 	"gopkg.in/yaml.v3"
@@ -27,6 +30,13 @@ import (
 type ResetProcessor struct {
 	target interface{}
 	paths  []tree.Path
+	// filename and sourceMap are set to record attributes position while parsing yaml
+	filename  string
+	sourceMap SourceMap
+	// included is the chain of files being inlined by `!include` tags, used to detect cycles
+	included []string
+	// workingDir is used to resolve `!include` paths when the yaml document isn't read from a file, like stdin
+	workingDir string
 }
 
 // UnmarshalYAML implement yaml.Unmarshaler
@@ -48,11 +58,15 @@ func (p *ResetProcessor) resolveReset(node *yaml.Node, path tree.Path) (*yaml.No
 		p.paths = append(p.paths, path)
 		return node, nil
 	}
+	if node.Tag == "!include" {
+		return p.resolveInclude(node, path)
+	}
 	switch node.Kind {
 	case yaml.SequenceNode:
 		var nodes []*yaml.Node
 		for idx, v := range node.Content {
 			next := path.Next(strconv.Itoa(idx))
+			p.recordPosition(next, v)
 			resolved, err := p.resolveReset(v, next)
 			if err != nil {
 				return nil, err
@@ -68,6 +82,7 @@ func (p *ResetProcessor) resolveReset(node *yaml.Node, path tree.Path) (*yaml.No
 		for idx, v := range node.Content {
 			if idx%2 == 0 {
 				key = v.Value
+				p.recordPosition(path.Next(key), v)
 			} else {
 				resolved, err := p.resolveReset(v, path.Next(key))
 				if err != nil {
@@ -83,6 +98,63 @@ func (p *ResetProcessor) resolveReset(node *yaml.Node, path tree.Path) (*yaml.No
 	return node, nil
 }
 
+// resolveInclude loads the yaml fragment referenced by an `!include` tag, relative to the current file,
+// so it can be used in place of node
+func (p *ResetProcessor) resolveInclude(node *yaml.Node, path tree.Path) (*yaml.Node, error) {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return nil, fmt.Errorf("%s: !include requires a file path", path)
+	}
+	file := node.Value
+	if !filepath.IsAbs(file) {
+		dir := p.workingDir
+		if p.filename != "" && p.filename != "-" {
+			dir = filepath.Dir(p.filename)
+		}
+		file = filepath.Join(dir, file)
+	}
+
+	included := p.included
+	if len(included) == 0 {
+		included = []string{filepath.Clean(p.filename)}
+	}
+	for _, f := range included {
+		if f == file {
+			return nil, fmt.Errorf("include cycle detected:\n%s\n include %s", included[0], strings.Join(append(included[1:], file), "\n include "))
+		}
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}, nil
+	}
+
+	filename, parent := p.filename, p.included
+	defer func() {
+		p.filename, p.included = filename, parent
+	}()
+	p.filename = file
+	p.included = append(included[:len(included):len(included)], file)
+	return p.resolveReset(doc.Content[0], path)
+}
+
+func (p *ResetProcessor) recordPosition(path tree.Path, node *yaml.Node) {
+	if p.sourceMap == nil {
+		return
+	}
+	p.sourceMap[path] = Position{
+		Filename: p.filename,
+		Line:     node.Line,
+		Column:   node.Column,
+	}
+}
+
 // Apply finds the go attributes matching recorded paths and reset them to zero value
 func (p *ResetProcessor) Apply(target any) error {
 	return p.applyNullOverrides(target, tree.NewPath())
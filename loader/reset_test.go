@@ -17,6 +17,7 @@
 package loader
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/compose-spec/compose-go/v2/types"
@@ -81,3 +82,59 @@ networks:
 	assert.NilError(t, err)
 	assert.Check(t, p.Networks["test"].External == false)
 }
+
+func TestIncludeTag(t *testing.T) {
+	p, err := Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{Filename: filepath.Join("testdata", "include-tag", "compose.yaml")},
+		},
+		Environment: types.Mapping{"BAR_VALUE": "bar"},
+	}, func(options *Options) {
+		options.SkipNormalization = true
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["foo"].Environment, types.NewMappingWithEquals([]string{"FOO=foo", "BAR=bar"}))
+	labels := types.Labels{
+		"com.example.team": "compose",
+		"com.example.tier": "backend",
+	}
+	assert.DeepEqual(t, p.Services["foo"].Labels, labels)
+	assert.DeepEqual(t, p.Services["bar"].Labels, labels)
+}
+
+func TestIncludeTagWithoutFilename(t *testing.T) {
+	workingDir, err := filepath.Abs(filepath.Join("testdata", "include-tag"))
+	assert.NilError(t, err)
+	p, err := Load(types.ConfigDetails{
+		WorkingDir: workingDir,
+		ConfigFiles: []types.ConfigFile{
+			{
+				Filename: "-",
+				Content: []byte(`
+name: include-tag
+services:
+  foo:
+    image: foo
+    labels: !include fragments/labels.yaml
+`),
+			},
+		},
+	}, func(options *Options) {
+		options.SkipNormalization = true
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.Services["foo"].Labels, types.Labels{
+		"com.example.team": "compose",
+		"com.example.tier": "backend",
+	})
+}
+
+func TestIncludeTagCycle(t *testing.T) {
+	_, err := Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{Filename: filepath.Join("testdata", "include-tag", "cycle.yaml")},
+		},
+	})
+	assert.ErrorContains(t, err, "include cycle detected")
+	assert.ErrorContains(t, err, "include "+filepath.Join("testdata", "include-tag", "cycle.yaml"))
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package loader
+
+import (
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/tree"
+)
+
+// Position is a location within a compose file
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+// SourceMap records the position of compose attributes in the compose files, indexed by their path in the model.
+// When the same attribute is declared by multiple files, the last one (which takes precedence) is recorded
+type SourceMap map[tree.Path]Position
+
+// Lookup returns the position of the attribute at path, or the position of the closest parent attribute if
+// path is not recorded (typically, for a missing required attribute)
+func (s SourceMap) Lookup(path tree.Path) (Position, bool) {
+	for path != "" {
+		if pos, ok := s[path]; ok {
+			return pos, true
+		}
+		path = path.Parent()
+	}
+	return Position{}, false
+}
+
+// SourceError is returned by the loader when Options.TrackSourcePositions is enabled, for an error which can
+// be attributed to a specific attribute in the compose files
+type SourceError struct {
+	// Path is the path of the attribute in the compose model
+	Path tree.Path
+	// Position is the position of the attribute in the compose files
+	Position Position
+	Err      error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
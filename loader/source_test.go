@@ -0,0 +1,91 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package loader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"gotest.tools/v3/assert"
+)
+
+func TestSourcePositionSchemaError(t *testing.T) {
+	config := types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{
+				Filename: "compose.yaml",
+				Content: []byte(`
+name: test
+services:
+  foo:
+    image: foo
+  bar:
+    image: bar
+`),
+			},
+			{
+				Filename: "compose.override.yaml",
+				Content: []byte(`
+services:
+  bar:
+    ports:
+      - 8080:80
+      - target: 80
+        published: [8080]
+`),
+			},
+		},
+	}
+
+	_, err := LoadWithContext(context.Background(), config, func(options *Options) {
+		options.TrackSourcePositions = true
+	})
+	var sourceErr *SourceError
+	assert.Assert(t, errors.As(err, &sourceErr))
+	assert.Equal(t, sourceErr.Path.String(), "services.bar.ports.1.published")
+	assert.Equal(t, sourceErr.Position, Position{Filename: "compose.override.yaml", Line: 7, Column: 9})
+	assert.ErrorContains(t, err, "compose.override.yaml:7:9: services.bar.ports.1.published must be a string or integer")
+
+	_, err = LoadWithContext(context.Background(), config)
+	assert.Assert(t, !errors.As(err, &sourceErr))
+	assert.ErrorContains(t, err, "validating compose.override.yaml: services.bar.ports.1.published must be a string or integer")
+}
+
+func TestSourcePositionMissingAttribute(t *testing.T) {
+	_, err := LoadWithContext(context.Background(), types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{
+				Filename: "compose.yaml",
+				Content: []byte(`
+name: test
+services:
+  foo:
+    image: foo
+    healthcheck:
+      intervals: 10s
+`),
+			},
+		},
+	}, func(options *Options) {
+		options.TrackSourcePositions = true
+	})
+	var sourceErr *SourceError
+	assert.Assert(t, errors.As(err, &sourceErr))
+	assert.Equal(t, sourceErr.Position, Position{Filename: "compose.yaml", Line: 6, Column: 5})
+}
@@ -18,9 +18,12 @@ package loader
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/compose-spec/compose-go/v2/types"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -58,9 +61,95 @@ func TestJSONMarshalProject(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Check(t, is.Equal(expected, string(actual)))
 
-	_, err = Load(buildConfigDetails(expected, map[string]string{}), func(options *Options) {
+	loaded, err := Load(buildConfigDetails(expected, map[string]string{}), func(options *Options) {
 		options.SkipNormalization = true
 		options.SkipConsistencyCheck = true
+		options.SkipResolveEnvironment = true
 	})
 	assert.NilError(t, err)
+
+	// Make sure JSON representation is lossless
+	actual, err = json.MarshalIndent(loaded, "", "  ")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(expected, string(actual)))
+}
+
+func TestMarshalProjectCompact(t *testing.T) {
+	workingDir, err := os.Getwd()
+	assert.NilError(t, err)
+	homeDir, err := os.UserHomeDir()
+	assert.NilError(t, err)
+
+	load := func(yaml string) *types.Project {
+		p, err := Load(buildConfigDetails(yaml, map[string]string{}), func(options *Options) {
+			options.SkipConsistencyCheck = true
+			options.SkipResolveEnvironment = true
+		})
+		assert.NilError(t, err)
+		return p
+	}
+	project := load(fullExampleYAML(workingDir, homeDir))
+
+	compact, err := project.MarshalYAMLWithOptions(types.WithoutDefaultValues(), types.WithShortSyntax())
+	assert.NilError(t, err)
+	verbose, err := project.MarshalYAML()
+	assert.NilError(t, err)
+	assert.Assert(t, len(compact) < len(verbose))
+
+	// Make sure compact representation is lossless
+	expected, err := json.MarshalIndent(project, "", "  ")
+	assert.NilError(t, err)
+	actual, err := json.MarshalIndent(load(string(compact)), "", "  ")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(expected), string(actual)))
+
+	project = load(`
+name: compact
+services:
+  web:
+    build: .
+    ports: ["8080:80", "127.0.0.1:53:53/udp", "[::1]::443"]
+    depends_on: [db]
+    volumes: ["./src:/src", "data:/data:ro", "/cache"]
+    secrets: [token]
+  db:
+    image: postgres
+volumes:
+  data: {}
+secrets:
+  token:
+    file: ./token
+`)
+	compact, err = project.MarshalYAMLWithOptions(types.WithoutDefaultValues(), types.WithShortSyntax())
+	assert.NilError(t, err)
+	assert.Equal(t, string(compact), fmt.Sprintf(`name: compact
+services:
+  db:
+    image: postgres
+    networks:
+      - default
+  web:
+    build: %[3]s
+    depends_on:
+      - db
+    networks:
+      - default
+    ports:
+      - "8080:80"
+      - "127.0.0.1:53:53/udp"
+      - "[::1]::443"
+    secrets:
+      - token
+    volumes:
+      - %[1]s:/src
+      - data:/data:ro
+      - /cache
+networks:
+  default: {}
+volumes:
+  data: {}
+secrets:
+  token:
+    file: %[2]s
+`, filepath.Join(workingDir, "src"), filepath.Join(workingDir, "token"), workingDir))
 }
@@ -18,18 +18,29 @@ package loader
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"net"
+	"path/filepath"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/errdefs"
 	"github.com/compose-spec/compose-go/v2/graph"
+	"github.com/compose-spec/compose-go/v2/paths"
 	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/compose-spec/compose-go/v2/utils"
+	"github.com/sirupsen/logrus"
 )
 
+// knownUlimits are the resource limits supported by the Linux kernel, as accepted by the Docker engine
+var knownUlimits = map[string]bool{
+	"core": true, "cpu": true, "data": true, "fsize": true, "locks": true, "memlock": true, "msgqueue": true,
+	"nice": true, "nofile": true, "nproc": true, "rss": true, "rtprio": true, "rttime": true, "sigpending": true,
+	"stack": true,
+}
+
 // checkConsistency validate a compose model is consistent
 func checkConsistency(project *types.Project) error {
-	for _, s := range project.Services {
+	for _, s := range project.OrderedServices() {
 		if s.Build == nil && s.Image == "" {
 			return fmt.Errorf("service %q has neither an image nor a build context specified: %w", s.Name, errdefs.ErrInvalid)
 		}
@@ -41,8 +52,9 @@ func checkConsistency(project *types.Project) error {
 
 			if len(s.Build.Platforms) > 0 && s.Platform != "" {
 				var found bool
-				for _, platform := range s.Build.Platforms {
-					if platform == s.Platform {
+				platform := normalizePlatform(s.Platform)
+				for _, p := range s.Build.Platforms {
+					if normalizePlatform(p) == platform {
 						found = true
 						break
 					}
@@ -62,11 +74,35 @@ func checkConsistency(project *types.Project) error {
 			}
 		}
 
+		checkNetworkModePorts(s)
+
+		for host, ips := range s.ExtraHosts {
+			for _, ip := range ips {
+				if ip != types.HostGateway && net.ParseIP(ip) == nil {
+					return fmt.Errorf("services.%s: invalid IP address %q for host '%s' in extra_hosts: %w", s.Name, ip, host, errdefs.ErrInvalid)
+				}
+			}
+		}
+
+		if s.Restart != "" && s.Deploy != nil && s.Deploy.RestartPolicy != nil {
+			checkRestartPolicyConflict(s)
+		}
+
+		if s.Deploy != nil {
+			checkResourcesConflict(s)
+		}
+
 		if s.HealthCheck != nil && len(s.HealthCheck.Test) > 0 {
-			switch s.HealthCheck.Test[0] {
-			case "CMD", "CMD-SHELL", "NONE":
+			test := s.HealthCheck.Test
+			switch test.Form() {
+			case types.HealthCheckExec, types.HealthCheckShell:
+				// an empty command string, typically set by an unset variable, is left to the engine
+				if len(test.Command()) == 0 {
+					return fmt.Errorf("services.%s: healthcheck.test %s requires a command: %w", s.Name, test.Form(), errdefs.ErrInvalid)
+				}
+			case types.HealthCheckNone:
 			default:
-				return errors.New(`healthcheck.test must start either by "CMD", "CMD-SHELL" or "NONE"`)
+				return fmt.Errorf(`services.%s: healthcheck.test must start either by "CMD", "CMD-SHELL" or "NONE": %w`, s.Name, errdefs.ErrInvalid)
 			}
 		}
 
@@ -75,24 +111,20 @@ func checkConsistency(project *types.Project) error {
 				return fmt.Errorf("service %q depends on undefined service %s: %w", s.Name, dependedService, errdefs.ErrInvalid)
 			}
 		}
-		// Check there isn't a cycle in depends_on declarations
-		if err := graph.InDependencyOrder(context.Background(), project, func(ctx context.Context, s string, config types.ServiceConfig) error {
-			return nil
-		}); err != nil {
-			return err
-		}
-
 		if strings.HasPrefix(s.NetworkMode, types.ServicePrefix) {
 			serviceName := s.NetworkMode[len(types.ServicePrefix):]
-			if _, err := project.GetServices(serviceName); err != nil {
-				return fmt.Errorf("service %q not found for network_mode 'service:%s'", serviceName, serviceName)
+			if serviceName == s.Name {
+				return fmt.Errorf("service %q can't share its own network stack with network_mode %q: %w", s.Name, s.NetworkMode, errdefs.ErrInvalid)
+			}
+			if _, ok := project.Services[serviceName]; !ok {
+				return fmt.Errorf("service %q refers to undefined service %s in network_mode %q: %w", s.Name, serviceName, s.NetworkMode, errdefs.ErrInvalid)
 			}
 		}
 
 		for _, volume := range s.Volumes {
 			if volume.Type == types.VolumeTypeVolume && volume.Source != "" { // non anonymous volumes
 				if _, ok := project.Volumes[volume.Source]; !ok {
-					return fmt.Errorf("service %q refers to undefined volume %s: %w", s.Name, volume.Source, errdefs.ErrInvalid)
+					return fmt.Errorf("service %q refers to undefined volume %s, mounted at %s: %w", s.Name, volume.Source, volume.Target, errdefs.ErrInvalid)
 				}
 			}
 		}
@@ -123,16 +155,34 @@ func checkConsistency(project *types.Project) error {
 			s.Deploy.Replicas = s.Scale
 		}
 
+		if s.Deploy != nil && s.Deploy.Resources.Limits != nil {
+			if err := checkResource(s.Name, "limits", s.Deploy.Resources.Limits); err != nil {
+				return err
+			}
+		}
+		if s.Deploy != nil && s.Deploy.Resources.Reservations != nil {
+			if err := checkResource(s.Name, "reservations", s.Deploy.Resources.Reservations); err != nil {
+				return err
+			}
+		}
+
 		if s.GetScale() > 1 && s.ContainerName != "" {
 			attr := "scale"
 			if s.Scale == nil {
 				attr = "deploy.replicas"
 			}
-			return fmt.Errorf("services.%s: can't set container_name and %s as container name must be unique: %w", attr,
-				s.Name, errdefs.ErrInvalid)
+			return fmt.Errorf("services.%s: can't set container_name and %s as container name must be unique: %w", s.Name,
+				attr, errdefs.ErrInvalid)
 		}
 	}
 
+	// Check there isn't a cycle in depends_on declarations
+	if err := graph.InDependencyOrder(context.Background(), project, func(ctx context.Context, s string, config types.ServiceConfig) error {
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	for name, secret := range project.Secrets {
 		if secret.External {
 			continue
@@ -144,3 +194,158 @@ func checkConsistency(project *types.Project) error {
 
 	return nil
 }
+
+// checkContainerNames rejects services declaring the same container_name. It is expected to run once profiles have
+// been applied, so that services which can't run together are not reported
+func checkContainerNames(project *types.Project) error {
+	byName := map[string][]types.ServiceConfig{}
+	for _, name := range project.ServiceNames() {
+		s := project.Services[name]
+		if s.ContainerName != "" {
+			byName[s.ContainerName] = append(byName[s.ContainerName], s)
+		}
+	}
+	for _, containerName := range utils.MapKeys(byName) {
+		services := byName[containerName]
+		if len(services) > 1 {
+			return fmt.Errorf("services %q and %q declare the same container_name %q: %w",
+				services[0].Name, services[1].Name, containerName, errdefs.ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// checkNetworkModePorts warns when a service publishes or exposes ports while its network_mode makes them ineffective
+func checkNetworkModePorts(s types.ServiceConfig) {
+	switch {
+	case s.NetworkMode == "none":
+		if len(s.Ports) > 0 {
+			logrus.Warnf("services.%s: ports are ignored as network_mode is %q", s.Name, s.NetworkMode)
+		}
+		if len(s.Expose) > 0 {
+			logrus.Warnf("services.%s: expose is ignored as network_mode is %q", s.Name, s.NetworkMode)
+		}
+	case strings.HasPrefix(s.NetworkMode, types.ContainerPrefix), strings.HasPrefix(s.NetworkMode, types.ServicePrefix):
+		if len(s.Ports) > 0 {
+			logrus.Warnf("services.%s: ports are ignored as network_mode is %q, ports must be published by the container owning the network stack",
+				s.Name, s.NetworkMode)
+		}
+	}
+}
+
+// checkBindMounts rejects bind mounts which source lies outside of dir
+func checkBindMounts(project *types.Project, dir string) error {
+	for _, s := range project.OrderedServices() {
+		for _, volume := range s.Volumes {
+			if volume.Type != types.VolumeTypeBind {
+				continue
+			}
+			if !isBindSourceWithin(project.WorkingDir, volume.Source, dir) {
+				return fmt.Errorf("services.%s: bind mount source %s is outside of %s: %w", s.Name, volume.Source, dir, errdefs.ErrInvalid)
+			}
+		}
+	}
+	for name, volume := range project.Volumes {
+		if volume.Driver != "" && volume.Driver != "local" {
+			continue
+		}
+		device, ok := volume.DriverOpts["device"]
+		if !ok || !strings.Contains(volume.DriverOpts["o"], "bind") {
+			continue
+		}
+		if !isBindSourceWithin(project.WorkingDir, device, dir) {
+			return fmt.Errorf("volumes.%s: bind device %s is outside of %s: %w", name, device, dir, errdefs.ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// isBindSourceWithin resolves source the way the engine would, relative to workingDir, and checks it lies within dir
+func isBindSourceWithin(workingDir, source, dir string) bool {
+	source = paths.ExpandUser(source)
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(workingDir, source)
+	}
+	return paths.IsWithin(dir, source)
+}
+
+// checkUlimitNames warns about service ulimits which are not supported by the Linux kernel
+func checkUlimitNames(project *types.Project) {
+	for _, s := range project.OrderedServices() {
+		for name := range s.Ulimits {
+			if !knownUlimits[name] {
+				logrus.Warnf("services.%s: unknown ulimit %q", s.Name, name)
+			}
+		}
+	}
+}
+
+// checkWorkingDirs warns about service working_dir which are not absolute paths, as those are resolved relative to
+// the image WORKDIR
+func checkWorkingDirs(project *types.Project) {
+	for _, s := range project.OrderedServices() {
+		if s.WorkingDir != "" && !paths.IsAbs(s.WorkingDir) {
+			logrus.Warnf("services.%s: working_dir %q is not an absolute path, it will be resolved relative to the image WORKDIR", s.Name, s.WorkingDir)
+		}
+	}
+}
+
+// checkRestartPolicyConflict warns when `restart` and `deploy.restart_policy` don't describe the same policy
+func checkRestartPolicyConflict(s types.ServiceConfig) {
+	restart, err := types.ParseRestartPolicy(s.Restart)
+	if err != nil {
+		return
+	}
+	deploy, err := types.ServiceConfig{Deploy: s.Deploy}.GetRestartPolicy()
+	if err != nil {
+		return
+	}
+	condition := restart.Condition
+	if condition == types.RestartPolicyUnlessStopped {
+		condition = types.RestartPolicyAlways
+	}
+	conflict := condition != deploy.Condition
+	if restart.MaxAttempts != nil && deploy.MaxAttempts != nil && *restart.MaxAttempts != *deploy.MaxAttempts {
+		conflict = true
+	}
+	if conflict {
+		logrus.Warnf("services.%s: restart %q conflicts with deploy.restart_policy, restart takes precedence", s.Name, s.Restart)
+	}
+}
+
+// checkResourcesConflict warns when service-level resource attributes and `deploy.resources` set distinct limits
+func checkResourcesConflict(s types.ServiceConfig) {
+	if limits := s.Deploy.Resources.Limits; limits != nil {
+		if s.MemLimit != 0 && limits.MemoryBytes != 0 && s.MemLimit != limits.MemoryBytes {
+			logrus.Warnf("services.%s: mem_limit %d conflicts with deploy.resources.limits.memory %d", s.Name, s.MemLimit, limits.MemoryBytes)
+		}
+		if cpus, err := limits.CPUs(); err == nil && s.CPUS != 0 && cpus != 0 && s.CPUS != cpus {
+			logrus.Warnf("services.%s: cpus %v conflicts with deploy.resources.limits.cpus %s", s.Name, s.CPUS, limits.NanoCPUs)
+		}
+		if s.PidsLimit != 0 && limits.Pids != 0 && s.PidsLimit != limits.Pids {
+			logrus.Warnf("services.%s: pids_limit %d conflicts with deploy.resources.limits.pids %d", s.Name, s.PidsLimit, limits.Pids)
+		}
+	}
+	if reservations := s.Deploy.Resources.Reservations; reservations != nil {
+		if s.MemReservation != 0 && reservations.MemoryBytes != 0 && s.MemReservation != reservations.MemoryBytes {
+			logrus.Warnf("services.%s: mem_reservation %d conflicts with deploy.resources.reservations.memory %d",
+				s.Name, s.MemReservation, reservations.MemoryBytes)
+		}
+	}
+}
+
+func checkResource(service string, kind string, resource *types.Resource) error {
+	cpus, err := resource.CPUs()
+	if err != nil {
+		return fmt.Errorf("services.%s.deploy.resources.%s.cpus: %s: %w", service, kind, err, errdefs.ErrInvalid)
+	}
+	if cpus < 0 {
+		return fmt.Errorf("services.%s.deploy.resources.%s.cpus: must not be negative, got %s: %w",
+			service, kind, resource.NanoCPUs, errdefs.ErrInvalid)
+	}
+	if resource.MemoryBytes < 0 {
+		return fmt.Errorf("services.%s.deploy.resources.%s.memory: must not be negative, got %d: %w",
+			service, kind, resource.MemoryBytes, errdefs.ErrInvalid)
+	}
+	return nil
+}
@@ -17,6 +17,9 @@
 package loader
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -60,7 +63,7 @@ func TestValidateNamedVolume(t *testing.T) {
 		},
 	}
 	err := checkConsistency(project)
-	assert.Error(t, err, `service "myservice" refers to undefined volume myVolume: invalid compose project`)
+	assert.Error(t, err, `service "myservice" refers to undefined volume myVolume, mounted at /use/local: invalid compose project`)
 
 	project.Volumes = types.Volumes(map[string]types.VolumeConfig{
 		"myVolume": {
@@ -117,7 +120,7 @@ func TestValidateNetworkMode(t *testing.T) {
 			},
 		}
 		err := checkConsistency(project)
-		assert.Error(t, err, `service "nonexistentservice" not found for network_mode 'service:nonexistentservice'`)
+		assert.Error(t, err, `service "myservice2" refers to undefined service nonexistentservice in network_mode "service:nonexistentservice": invalid compose project`)
 	})
 
 	t.Run("network_mode container", func(t *testing.T) {
@@ -139,6 +142,42 @@ func TestValidateNetworkMode(t *testing.T) {
 		assert.NilError(t, err)
 	})
 
+	t.Run("network_mode service self reference", func(t *testing.T) {
+		project := &types.Project{
+			Services: types.Services{
+				"myservice": {
+					Name:        "myservice",
+					Image:       "scratch",
+					NetworkMode: "service:myservice",
+				},
+			},
+		}
+		err := checkConsistency(project)
+		assert.Error(t, err, `service "myservice" can't share its own network stack with network_mode "service:myservice": invalid compose project`)
+	})
+
+	t.Run("network_mode service & networks can't both be defined", func(t *testing.T) {
+		project := &types.Project{
+			Networks: types.Networks{"mynetwork": types.NetworkConfig{}},
+			Services: types.Services{
+				"myservice1": {
+					Name:  "myservice1",
+					Image: "scratch",
+				},
+				"myservice2": {
+					Name:        "myservice2",
+					Image:       "scratch",
+					NetworkMode: "service:myservice1",
+					Networks: map[string]*types.ServiceNetworkConfig{
+						"mynetwork": {},
+					},
+				},
+			},
+		}
+		err := checkConsistency(project)
+		assert.Error(t, err, "service myservice2 declares mutually exclusive `network_mode` and `networks`: invalid compose project")
+	})
+
 	t.Run("network_mode & networks can't both be defined", func(t *testing.T) {
 		project := &types.Project{
 			Networks: types.Networks{"mynetwork": types.NetworkConfig{}},
@@ -259,3 +298,607 @@ func TestValidateDependsOn(t *testing.T) {
 	err := checkConsistency(&project)
 	assert.Error(t, err, `service "myservice" depends on undefined service missingservice: invalid compose project`)
 }
+
+func TestValidateDeployResources(t *testing.T) {
+	project := &types.Project{
+		Services: types.Services{
+			"myservice": {
+				Name:  "myservice",
+				Image: "my/service",
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Limits: &types.Resource{
+							NanoCPUs:    "1.5",
+							MemoryBytes: 1024,
+						},
+						Reservations: &types.Resource{
+							NanoCPUs: "-1",
+						},
+					},
+				},
+			},
+		},
+	}
+	err := checkConsistency(project)
+	assert.Error(t, err, `services.myservice.deploy.resources.reservations.cpus: must not be negative, got -1: invalid compose project`)
+
+	project.Services["myservice"].Deploy.Resources.Reservations.NanoCPUs = "one"
+	err = checkConsistency(project)
+	assert.Error(t, err, `services.myservice.deploy.resources.reservations.cpus: invalid cpus value "one": invalid compose project`)
+
+	project.Services["myservice"].Deploy.Resources.Reservations.NanoCPUs = "0"
+	project.Services["myservice"].Deploy.Resources.Limits.MemoryBytes = 0
+	err = checkConsistency(project)
+	assert.NilError(t, err)
+
+	project.Services["myservice"].Deploy.Resources.Reservations.NanoCPUs = "0.5"
+	project.Services["myservice"].Deploy.Resources.Limits.MemoryBytes = -1
+	err = checkConsistency(project)
+	assert.Error(t, err, `services.myservice.deploy.resources.limits.memory: must not be negative, got -1: invalid compose project`)
+}
+
+func TestValidateDependsOnCycle(t *testing.T) {
+	project := &types.Project{
+		Services: types.Services{
+			"web": {
+				Name:      "web",
+				Image:     "scratch",
+				DependsOn: types.DependsOnConfig{"db": {Required: true}},
+			},
+			"db": {
+				Name:      "db",
+				Image:     "scratch",
+				DependsOn: types.DependsOnConfig{"web": {Required: true}},
+			},
+			"self": {
+				Name:  "self",
+				Image: "scratch",
+			},
+		},
+	}
+	err := checkConsistency(project)
+	assert.Error(t, err, "dependency cycle detected: db -> web -> db")
+
+	project.Services["db"] = types.ServiceConfig{Name: "db", Image: "scratch"}
+	project.Services["self"] = types.ServiceConfig{
+		Name:      "self",
+		Image:     "scratch",
+		DependsOn: types.DependsOnConfig{"self": {Required: true}},
+	}
+	err = checkConsistency(project)
+	assert.Error(t, err, "dependency cycle detected: self -> self")
+}
+
+func TestValidateImageOrBuild(t *testing.T) {
+	tests := []struct {
+		file    string
+		wantErr string
+	}{
+		{
+			file:    "neither.yaml",
+			wantErr: `service "app" has neither an image nor a build context specified: invalid compose project`,
+		},
+		{
+			file:    "empty-build.yaml",
+			wantErr: `services.app.build: invalid build, context can't be blank (use "." to build from the project directory)`,
+		},
+		{
+			file: "valid.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", "image-or-build", tt.file))
+			assert.NilError(t, err)
+			_, err = Load(buildConfigDetails(string(b), nil))
+			if tt.wantErr != "" {
+				assert.Error(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestValidateReplicas(t *testing.T) {
+	tests := []struct {
+		file    string
+		wantErr string
+	}{
+		{
+			file:    "negative.yaml",
+			wantErr: `services.web.deploy.replicas: must be a non-negative integer, got -1`,
+		},
+		{
+			file: "zero.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", "replicas", tt.file))
+			assert.NilError(t, err)
+			_, err = Load(buildConfigDetails(string(b), nil))
+			if tt.wantErr != "" {
+				assert.Error(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestValidateWorkingDir(t *testing.T) {
+	b, err := os.ReadFile("testdata/compose-working-dir.yaml")
+	assert.NilError(t, err)
+
+	buf, reset := patchLogrus()
+	defer reset()
+
+	p, err := Load(buildConfigDetails(string(b), nil))
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["relative"].WorkingDir, "app/src")
+	assert.Assert(t, !strings.Contains(buf.String(), "working_dir"), buf.String())
+
+	_, err = Load(buildConfigDetails(string(b), nil), func(options *Options) {
+		options.WarnRelativeWorkingDir = true
+	})
+	assert.NilError(t, err)
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, `services.relative: working_dir \"app/src\" is not an absolute path`), out)
+	assert.Assert(t, !strings.Contains(out, "services.absolute"), out)
+	assert.Assert(t, !strings.Contains(out, "services.windows"), out)
+}
+
+func TestValidateUndeclaredResources(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-undeclared-resources.yaml"))
+	assert.NilError(t, err)
+	project, err := loadYAML(string(b))
+	assert.NilError(t, err)
+
+	// bind mounts, tmpfs and anonymous volumes are ignored
+	assert.NilError(t, checkConsistency(project.WithServicesDisabled("volume", "network", "config", "secret")))
+
+	tests := map[string]string{
+		"volume":  `service "volume" refers to undefined volume data, mounted at /data: invalid compose project`,
+		"network": `service "network" refers to undefined network backend: invalid compose project`,
+		"config":  `service "config" refers to undefined config settings: invalid compose project`,
+		"secret":  `service "secret" refers to undefined secret password: invalid compose project`,
+	}
+	for service, expected := range tests {
+		t.Run(service, func(t *testing.T) {
+			p, err := project.WithSelectedServices([]string{service}, types.IgnoreDependencies)
+			assert.NilError(t, err)
+			assert.Error(t, checkConsistency(p), expected)
+		})
+	}
+}
+
+func TestValidateForPlatform(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-platform.yaml"))
+	assert.NilError(t, err)
+	project, err := Load(buildConfigDetails(string(b), nil))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, ValidateForPlatform(project, "windows"), []string{
+		"services.linux-only: cap_add is not supported on windows",
+		"services.linux-only: privileged is not supported on windows",
+		"services.linux-only: tmpfs volume /cache is not supported on windows",
+	})
+	assert.DeepEqual(t, ValidateForPlatform(project, "linux/arm64"), []string{
+		"services.windows-only: isolation is not supported on linux",
+	})
+	assert.Check(t, len(ValidateForPlatform(project, "darwin")) == 0)
+}
+
+func TestValidateRestart(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-restart.yaml"))
+	assert.NilError(t, err)
+
+	buf, reset := patchLogrus()
+	defer reset()
+
+	_, err = Load(buildConfigDetails(string(b), nil))
+	assert.NilError(t, err)
+
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, `services.conflicting: restart \"always\" conflicts with deploy.restart_policy`), out)
+	assert.Assert(t, !strings.Contains(out, "services.consistent"), out)
+
+	_, err = Load(buildConfigDetails(`
+name: restart
+services:
+  invalid:
+    image: busybox
+    restart: on-failure:many
+`, nil))
+	assert.Error(t, err, `services.invalid.restart: invalid restart policy "on-failure:many", max-retries must be a non-negative integer`)
+
+	_, err = Load(buildConfigDetails(`
+name: restart
+services:
+  typo:
+    image: busybox
+    restart: allways
+`, nil))
+	assert.ErrorContains(t, err, `services.typo.restart: invalid restart policy "allways"`)
+
+	_, err = Load(buildConfigDetails(`
+name: restart
+services:
+  invalid:
+    image: busybox
+    deploy:
+      restart_policy:
+        condition: unless-stopped
+`, nil))
+	assert.ErrorContains(t, err, `services.invalid.deploy.restart_policy.condition: invalid restart_policy condition "unless-stopped"`)
+}
+
+func TestValidateUlimits(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "compose-ulimits.yaml"))
+	assert.NilError(t, err)
+
+	buf, reset := patchLogrus()
+	defer reset()
+
+	p, err := Load(buildConfigDetails(string(b), nil))
+	assert.NilError(t, err)
+	ulimits := p.Services["app"].Ulimits
+	assert.DeepEqual(t, *ulimits["nproc"], types.UlimitsConfig{Single: 65535})
+	assert.DeepEqual(t, *ulimits["nofile"], types.UlimitsConfig{Soft: 20000, Hard: 40000})
+	assert.Assert(t, !strings.Contains(buf.String(), "unknown ulimit"), buf.String())
+
+	_, err = Load(buildConfigDetails(string(b), nil), func(options *Options) {
+		options.WarnUnknownUlimits = true
+	})
+	assert.NilError(t, err)
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, `services.app: unknown ulimit \"custom\"`), out)
+	assert.Assert(t, !strings.Contains(out, "nofile"), out)
+
+	// ulimits are rendered using the syntax they were declared with
+	yml, err := p.MarshalYAML()
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(yml), `
+      nofile:
+        soft: 20000
+        hard: 40000
+      nproc: 65535
+`), string(yml))
+
+	_, err = Load(buildConfigDetails(`
+name: ulimits
+services:
+  app:
+    image: busybox
+    ulimits:
+      nofile:
+        soft: 40000
+        hard: 20000
+`, nil))
+	assert.Error(t, err, `services.app.ulimits.nofile: soft limit 40000 must not exceed hard limit 20000`)
+}
+
+func TestValidateContainerNames(t *testing.T) {
+	_, err := Load(buildConfigDetails(`
+name: container-names
+services:
+  front:
+    image: nginx
+    container_name: web
+  back:
+    image: nginx
+    container_name: web
+`, nil))
+	assert.Error(t, err, `services "back" and "front" declare the same container_name "web": invalid compose project`)
+
+	// only services enabled by the active profiles are checked
+	profiles := `
+name: container-names
+services:
+  dev:
+    image: nginx
+    container_name: web
+    profiles: [dev]
+  prod:
+    image: nginx
+    container_name: web
+    profiles: [prod]
+`
+	_, err = Load(buildConfigDetails(profiles, nil), WithProfiles([]string{"dev"}))
+	assert.NilError(t, err)
+
+	_, err = Load(buildConfigDetails(profiles, nil), WithProfiles([]string{"dev", "prod"}))
+	assert.Error(t, err, `services "dev" and "prod" declare the same container_name "web": invalid compose project`)
+
+	_, err = Load(buildConfigDetails(`
+name: container-names
+services:
+  web:
+    image: nginx
+    container_name: web
+    deploy:
+      replicas: 2
+`, nil))
+	assert.Error(t, err, `services.web: can't set container_name and deploy.replicas as container name must be unique: invalid compose project`)
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	for platform, expected := range map[string]string{
+		"linux":            "linux",
+		"Linux/AMD64":      "linux/amd64",
+		"linux/x86_64":     "linux/amd64",
+		"linux/aarch64":    "linux/arm64",
+		"linux/arm64/v8":   "linux/arm64",
+		"linux/arm":        "linux/arm/v7",
+		"linux/arm/7":      "linux/arm/v7",
+		"linux/armel":      "linux/arm/v6",
+		"linux/arm/v6":     "linux/arm/v6",
+		"linux/i386":       "linux/386",
+		"macos/arm64":      "darwin/arm64",
+		" windows/amd64 ":  "windows/amd64",
+		"linux/riscv64":    "linux/riscv64",
+		"linux/ppc64le/v9": "linux/ppc64le/v9",
+	} {
+		assert.Equal(t, normalizePlatform(platform), expected, platform)
+	}
+}
+
+func TestValidateBuildPlatforms(t *testing.T) {
+	_, err := Load(buildConfigDetails(`
+name: platforms
+services:
+  app:
+    platform: linux/arm
+    build:
+      context: .
+      platforms: [linux/amd64, linux/arm/v7]
+`, nil))
+	assert.NilError(t, err)
+
+	_, err = Load(buildConfigDetails(`
+name: platforms
+services:
+  app:
+    platform: linux/arm64
+    build:
+      context: .
+      platforms: [linux/amd64, linux/arm/v7]
+`, nil))
+	assert.Error(t, err, `service.build.platforms MUST include service.platform "linux/arm64": invalid compose project`)
+}
+
+func TestValidateConsistencyOrder(t *testing.T) {
+	// errors are reported for services in name order, not map iteration order
+	for i := 0; i < 10; i++ {
+		_, err := Load(buildConfigDetails(`
+name: order
+services:
+  zz:
+    image: busybox
+    depends_on: [missing]
+  aa:
+    image: busybox
+    depends_on: [missing]
+  mm:
+    image: busybox
+    depends_on: [missing]
+`, nil))
+		assert.Error(t, err, `service "aa" depends on undefined service missing: invalid compose project`)
+	}
+}
+
+func TestValidateHealthCheckTest(t *testing.T) {
+	p, err := Load(buildConfigDetails(`
+name: healthcheck
+services:
+  shell:
+    image: busybox
+    healthcheck:
+      test: curl -f http://localhost
+  explicit-shell:
+    image: busybox
+    healthcheck:
+      test: ["CMD-SHELL", "curl -f http://localhost"]
+  exec:
+    image: busybox
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+  none:
+    image: busybox
+    healthcheck:
+      test: ["NONE"]
+`, nil))
+	assert.NilError(t, err)
+	shell := p.Services["shell"].HealthCheck.Test
+	assert.DeepEqual(t, shell, types.HealthCheckTest{"CMD-SHELL", "curl -f http://localhost"})
+	assert.Equal(t, shell.Form(), types.HealthCheckShell)
+	assert.DeepEqual(t, p.Services["explicit-shell"].HealthCheck.Test, shell)
+	exec := p.Services["exec"].HealthCheck.Test
+	assert.Equal(t, exec.Form(), types.HealthCheckExec)
+	assert.DeepEqual(t, exec.Command(), []string{"curl", "-f", "http://localhost"})
+	assert.Equal(t, p.Services["none"].HealthCheck.Test.Form(), types.HealthCheckNone)
+
+	p, err = Load(buildConfigDetails(`
+name: healthcheck
+services:
+  empty:
+    image: busybox
+    healthcheck:
+      test: ""
+  unset:
+    image: busybox
+    healthcheck:
+      test: ${HEALTHCHECK}
+  explicit-empty:
+    image: busybox
+    healthcheck:
+      test: ["CMD-SHELL", ""]
+`, nil))
+	assert.NilError(t, err)
+	empty := types.HealthCheckTest{"CMD-SHELL", ""}
+	assert.DeepEqual(t, p.Services["empty"].HealthCheck.Test, empty)
+	assert.DeepEqual(t, p.Services["unset"].HealthCheck.Test, empty)
+	assert.DeepEqual(t, p.Services["explicit-empty"].HealthCheck.Test, empty)
+
+	_, err = Load(buildConfigDetails(`
+name: healthcheck
+services:
+  exec:
+    image: busybox
+    healthcheck:
+      test: ["CMD"]
+`, nil))
+	assert.Error(t, err, "services.exec: healthcheck.test CMD requires a command: invalid compose project")
+
+	_, err = Load(buildConfigDetails(`
+name: healthcheck
+services:
+  shell:
+    image: busybox
+    healthcheck:
+      test: ["CMD-SHELL"]
+`, nil))
+	assert.Error(t, err, "services.shell: healthcheck.test CMD-SHELL requires a command: invalid compose project")
+
+	_, err = Load(buildConfigDetails(`
+name: healthcheck
+services:
+  shell:
+    image: busybox
+    healthcheck:
+      test: ["curl", "-f", "http://localhost"]
+`, nil))
+	assert.Error(t, err, `services.shell: healthcheck.test must start either by "CMD", "CMD-SHELL" or "NONE": invalid compose project`)
+}
+
+func TestValidateResourcesConflict(t *testing.T) {
+	buf, reset := patchLogrus()
+	defer reset()
+
+	p, err := Load(buildConfigDetails(`
+name: resources
+services:
+  conflicting:
+    image: busybox
+    mem_limit: 512m
+    cpus: 0.5
+    deploy:
+      resources:
+        limits:
+          memory: 1g
+          cpus: "0.5"
+  consistent:
+    image: busybox
+    mem_limit: 64m
+    memswap_limit: 128m
+    shm_size: 64m
+    cpus: 1.5
+    cpu_shares: 512
+    deploy:
+      resources:
+        limits:
+          memory: 64m
+`, nil))
+	assert.NilError(t, err)
+
+	consistent := p.Services["consistent"]
+	assert.Equal(t, consistent.MemLimit, types.UnitBytes(64*1024*1024))
+	assert.Equal(t, consistent.MemSwapLimit, types.UnitBytes(128*1024*1024))
+	assert.Equal(t, consistent.ShmSize, types.UnitBytes(64*1024*1024))
+	assert.Equal(t, consistent.CPUS, float32(1.5))
+	assert.Equal(t, consistent.CPUShares, int64(512))
+
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, "services.conflicting: mem_limit 536870912 conflicts with deploy.resources.limits.memory 1073741824"), out)
+	assert.Assert(t, !strings.Contains(out, "services.conflicting: cpus"), out)
+	assert.Assert(t, !strings.Contains(out, "services.consistent"), out)
+}
+
+func TestValidateNetworkModePorts(t *testing.T) {
+	buf, reset := patchLogrus()
+	defer reset()
+
+	_, err := Load(buildConfigDetails(`
+name: network-mode
+services:
+  isolated:
+    image: busybox
+    network_mode: none
+    ports: ["8080:80"]
+    expose: ["9090"]
+  shared:
+    image: busybox
+    network_mode: service:web
+    ports: ["8081:80"]
+  container:
+    image: busybox
+    network_mode: container:other
+    expose: ["9090"]
+  web:
+    image: busybox
+    ports: ["8082:80"]
+`, nil))
+	assert.NilError(t, err)
+
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, `services.isolated: ports are ignored as network_mode is \"none\"`), out)
+	assert.Assert(t, strings.Contains(out, `services.isolated: expose is ignored as network_mode is \"none\"`), out)
+	assert.Assert(t, strings.Contains(out, `services.shared: ports are ignored as network_mode is \"service:web\"`), out)
+	assert.Assert(t, !strings.Contains(out, "services.container"), out)
+	assert.Assert(t, !strings.Contains(out, "services.web"), out)
+}
+
+func TestValidateScale(t *testing.T) {
+	p, err := Load(buildConfigDetails(`
+name: scale
+services:
+  web:
+    image: nginx
+    scale: 3
+    deploy:
+      resources:
+        limits:
+          memory: 64m
+  single:
+    image: nginx
+    scale: 1
+    container_name: single
+`, nil))
+	assert.NilError(t, err)
+	web := p.Services["web"]
+	assert.Equal(t, *web.Scale, 3)
+	// scale is reconciled with deploy.replicas
+	assert.Equal(t, *web.Deploy.Replicas, 3)
+	assert.Equal(t, web.GetScale(), 3)
+
+	_, err = Load(buildConfigDetails(`
+name: scale
+services:
+  web:
+    image: nginx
+    scale: 2
+    deploy:
+      replicas: 3
+`, nil))
+	assert.Error(t, err, `services.web: can't set distinct values on 'scale' and 'deploy.replicas': invalid compose project`)
+
+	_, err = Load(buildConfigDetails(`
+name: scale
+services:
+  web:
+    image: nginx
+    scale: -1
+`, nil))
+	assert.ErrorContains(t, err, "services.web.scale")
+
+	_, err = Load(buildConfigDetails(`
+name: scale
+services:
+  web:
+    image: nginx
+    scale: 2
+    container_name: web
+`, nil))
+	assert.Error(t, err, `services.web: can't set container_name and scale as container name must be unique: invalid compose project`)
+}
@@ -19,9 +19,11 @@ package override
 import (
 	"cmp"
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/tree"
+	"github.com/compose-spec/compose-go/v2/types"
 	"golang.org/x/exp/slices"
 )
 
@@ -43,6 +45,7 @@ func init() {
 	mergeSpecials["networks.*.ipam.config"] = mergeIPAMConfig
 	mergeSpecials["services.*.annotations"] = mergeToSequence
 	mergeSpecials["services.*.build"] = mergeBuild
+	mergeSpecials["services.*.build.annotations"] = mergeToSequence
 	mergeSpecials["services.*.build.args"] = mergeToSequence
 	mergeSpecials["services.*.build.additional_contexts"] = mergeToSequence
 	mergeSpecials["services.*.build.labels"] = mergeToSequence
@@ -57,9 +60,11 @@ func init() {
 	mergeSpecials["services.*.environment"] = mergeToSequence
 	mergeSpecials["services.*.extra_hosts"] = mergeToSequence
 	mergeSpecials["services.*.healthcheck.test"] = override
+	mergeSpecials["services.*.label_file"] = mergeToSequence
 	mergeSpecials["services.*.labels"] = mergeToSequence
 	mergeSpecials["services.*.logging"] = mergeLogging
 	mergeSpecials["services.*.networks"] = mergeNetworks
+	mergeSpecials["services.*.ports"] = mergePorts
 	mergeSpecials["services.*.sysctls"] = mergeToSequence
 	mergeSpecials["services.*.tmpfs"] = mergeToSequence
 	mergeSpecials["services.*.ulimits.*"] = mergeUlimit
@@ -160,6 +165,107 @@ func mergeNetworks(c any, o any, path tree.Path) (any, error) {
 	return mergeMappings(right, left, path)
 }
 
+// mergePorts merges ports keyed by target port, protocol and host IP, so that an override replaces a base port
+// mapping rather than publishing the same container port twice
+func mergePorts(c any, o any, p tree.Path) (any, error) {
+	base, ok := c.([]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot override %s", p)
+	}
+	other, ok := o.([]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot override %s", p)
+	}
+
+	// index overriding ports by key, so that they replace base ports in place
+	overrides := map[string]int{}
+	for i, port := range other {
+		mappings, _ := convertIntoPortMappings(port)
+		for _, m := range mappings {
+			overrides[portKey(m)] = i
+		}
+	}
+
+	var merged []any
+	placed := make([]bool, len(other))
+	for _, port := range base {
+		mappings, ok := convertIntoPortMappings(port)
+		if !ok || !slices.ContainsFunc(mappings, func(m map[string]any) bool {
+			_, overridden := overrides[portKey(m)]
+			return overridden
+		}) {
+			merged = append(merged, port)
+			continue
+		}
+		// a short syntax entry can declare a range, only keep the ports which are not overridden
+		for _, m := range mappings {
+			i, overridden := overrides[portKey(m)]
+			switch {
+			case !overridden:
+				merged = append(merged, m)
+			case !placed[i]:
+				merged = append(merged, other[i])
+				placed[i] = true
+			}
+		}
+	}
+	for i, port := range other {
+		if !placed[i] {
+			merged = append(merged, port)
+		}
+	}
+	return merged, nil
+}
+
+// convertIntoPortMappings converts a port entry into long syntax, returning false if it can't be parsed
+func convertIntoPortMappings(port any) ([]map[string]any, bool) {
+	var spec string
+	switch v := port.(type) {
+	case map[string]any:
+		return []map[string]any{v}, true
+	case int:
+		spec = fmt.Sprint(v)
+	case string:
+		spec = v
+	default:
+		return nil, false
+	}
+	configs, err := types.ParsePortConfig(spec)
+	if err != nil {
+		return nil, false
+	}
+	mappings := make([]map[string]any, len(configs))
+	for i, config := range configs {
+		m := map[string]any{"target": int(config.Target)}
+		if config.Mode != "" {
+			m["mode"] = config.Mode
+		}
+		if config.HostIP != "" {
+			m["host_ip"] = config.HostIP
+		}
+		if config.Published != "" {
+			m["published"] = config.Published
+		}
+		if config.Protocol != "" {
+			m["protocol"] = config.Protocol
+		}
+		mappings[i] = m
+	}
+	return mappings, true
+}
+
+func portKey(port map[string]any) string {
+	host, ok := port["host_ip"]
+	if !ok {
+		host = ""
+	}
+	protocol, ok := port["protocol"]
+	if !ok {
+		protocol = "tcp"
+	}
+	return fmt.Sprintf("%v:%v/%v", host, port["target"], protocol)
+}
+
 func mergeToSequence(c any, o any, _ tree.Path) (any, error) {
 	right := convertIntoSequence(c)
 	left := convertIntoSequence(o)
@@ -241,7 +347,12 @@ func convertIntoMapping(a any, defaultValue any) map[string]any {
 	case []any:
 		converted := map[string]any{}
 		for _, s := range v {
-			converted[s.(string)] = defaultValue
+			// each entry gets its own copy of the default value, as merge updates mappings in place
+			if m, ok := defaultValue.(map[string]any); ok {
+				converted[s.(string)] = maps.Clone(m)
+			} else {
+				converted[s.(string)] = defaultValue
+			}
 		}
 		return converted
 	}
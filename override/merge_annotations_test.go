@@ -112,3 +112,31 @@ services:
       - FOO=3
 `)
 }
+
+func TestMergeBuildAnnotations(t *testing.T) {
+	assertMergeYaml(t, `
+services:
+  test:
+    image: foo
+    build:
+      context: .
+      annotations:
+        FOO: BAR
+        QIX: ZOT
+`, `
+services:
+  test:
+    build:
+      annotations:
+        - QIX=OVERRIDE
+`, `
+services:
+  test:
+    image: foo
+    build:
+      context: .
+      annotations:
+        - FOO=BAR
+        - QIX=OVERRIDE
+`)
+}
@@ -0,0 +1,49 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package override
+
+import (
+	"testing"
+)
+
+func TestMergeDependsOnSequenceAndMapping(t *testing.T) {
+	assertMergeYaml(t, `
+services:
+  test:
+    image: foo
+    depends_on:
+      - db
+      - cache
+`, `
+services:
+  test:
+    depends_on:
+      cache:
+        condition: service_healthy
+`, `
+services:
+  test:
+    image: foo
+    depends_on:
+      db:
+        condition: service_started
+        required: true
+      cache:
+        condition: service_healthy
+        required: true
+`)
+}
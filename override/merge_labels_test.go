@@ -322,3 +322,28 @@ services:
         - FOO=3
 `)
 }
+
+func Test_mergeYamlLabelFiles(t *testing.T) {
+	assertMergeYaml(t, `
+services:
+  test:
+    image: foo
+    label_file:
+      - ./env=dev/labels
+      - ./common/labels
+`, `
+services:
+  test:
+    label_file:
+      - ./env=prod/labels
+      - ./common/labels
+`, `
+services:
+  test:
+    image: foo
+    label_file:
+      - ./env=dev/labels
+      - ./common/labels
+      - ./env=prod/labels
+`)
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package override
+
+import (
+	"testing"
+)
+
+func Test_mergeYamlPortsReplace(t *testing.T) {
+	assertMergeYaml(t, `
+services:
+  test:
+    image: foo
+    ports:
+      - "8080:80"
+      - "8443:443"
+      - "127.0.0.1:5432:5432"
+`, `
+services:
+  test:
+    ports:
+      - "9090:80"
+      - target: 5432
+        host_ip: 127.0.0.1
+        published: "15432"
+`, `
+services:
+  test:
+    image: foo
+    ports:
+      - "9090:80"
+      - "8443:443"
+      - target: 5432
+        host_ip: 127.0.0.1
+        published: "15432"
+`)
+}
+
+func Test_mergeYamlPortsAdd(t *testing.T) {
+	assertMergeYaml(t, `
+services:
+  test:
+    image: foo
+    ports:
+      - "8080:80"
+      - 53:53/udp
+`, `
+services:
+  test:
+    ports:
+      - "9090:80/udp"
+      - "127.0.0.1:8081:80"
+      - 53:53/tcp
+`, `
+services:
+  test:
+    image: foo
+    ports:
+      - "8080:80"
+      - 53:53/udp
+      - "9090:80/udp"
+      - "127.0.0.1:8081:80"
+      - 53:53/tcp
+`)
+}
+
+func Test_mergeYamlPortsRange(t *testing.T) {
+	assertMergeYaml(t, `
+services:
+  test:
+    image: foo
+    ports:
+      - "8000-8002:8000-8002"
+`, `
+services:
+  test:
+    ports:
+      - "9001:8001"
+`, `
+services:
+  test:
+    image: foo
+    ports:
+      - target: 8000
+        published: "8000"
+        protocol: tcp
+        mode: ingress
+      - "9001:8001"
+      - target: 8002
+        published: "8002"
+        protocol: tcp
+        mode: ingress
+`)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/compose-spec/compose-go/v2/format"
 	"github.com/compose-spec/compose-go/v2/tree"
+	"github.com/docker/go-connections/nat"
 )
 
 type indexer func(any, tree.Path) (string, error)
@@ -30,10 +31,16 @@ type indexer func(any, tree.Path) (string, error)
 // mergeSpecials defines the custom rules applied by compose when merging yaml trees
 var unique = map[tree.Path]indexer{}
 
+// conflicts defines the checks applied on sequences once unicity has been enforced, to detect incompatible entries
+var conflicts = map[tree.Path]func([]any, tree.Path) error{}
+
 func init() {
+	conflicts["services.*.ports"] = checkPortsConflicts
+
 	unique["networks.*.labels"] = keyValueIndexer
 	unique["networks.*.ipam.options"] = keyValueIndexer
 	unique["services.*.annotations"] = keyValueIndexer
+	unique["services.*.build.annotations"] = keyValueIndexer
 	unique["services.*.build.args"] = keyValueIndexer
 	unique["services.*.build.additional_contexts"] = keyValueIndexer
 	unique["services.*.build.extra_hosts"] = keyValueIndexer
@@ -51,6 +58,7 @@ func init() {
 	unique["services.*.env_file"] = envFileIndexer
 	unique["services.*.expose"] = exposeIndexer
 	unique["services.*.extra_hosts"] = keyValueIndexer
+	unique["services.*.label_file"] = envFileIndexer
 	unique["services.*.labels"] = keyValueIndexer
 	unique["services.*.links"] = keyValueIndexer
 	unique["services.*.networks.*.aliases"] = keyValueIndexer
@@ -100,6 +108,13 @@ func enforceUnicity(value any, p tree.Path) (any, error) {
 						keys[key] = len(seq) - 1
 					}
 				}
+				for pattern, check := range conflicts {
+					if p.Matches(pattern) {
+						if err := check(seq, p); err != nil {
+							return nil, err
+						}
+					}
+				}
 				return seq, nil
 			}
 		}
@@ -107,13 +122,19 @@ func enforceUnicity(value any, p tree.Path) (any, error) {
 	return value, nil
 }
 
-func keyValueIndexer(y any, _ tree.Path) (string, error) {
-	value := y.(string)
-	key, _, found := strings.Cut(value, "=")
-	if !found {
-		return value, nil
+func keyValueIndexer(y any, p tree.Path) (string, error) {
+	switch value := y.(type) {
+	case string:
+		key, _, found := strings.Cut(value, "=")
+		if !found {
+			return value, nil
+		}
+		return key, nil
+	case int, float64:
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("%s: unsupported value %v", p, y)
 	}
-	return key, nil
 }
 
 func volumeIndexer(y any, p tree.Path) (string, error) {
@@ -193,6 +214,47 @@ func portIndexer(y any, p tree.Path) (string, error) {
 	return "", nil
 }
 
+// checkPortsConflicts detects a published port bound to distinct target ports
+func checkPortsConflicts(ports []any, p tree.Path) error {
+	targets := map[string]string{}
+	for _, port := range ports {
+		value, ok := port.(map[string]any)
+		if !ok {
+			continue
+		}
+		published, ok := value["published"]
+		if !ok || published == "" {
+			continue
+		}
+		protocol, ok := value["protocol"]
+		if !ok {
+			protocol = "tcp"
+		}
+		target := fmt.Sprint(value["target"])
+		for _, port := range expandPublishedPorts(fmt.Sprint(published)) {
+			key := fmt.Sprintf("%v:%v/%v", value["host_ip"], port, protocol)
+			if other, ok := targets[key]; ok && other != target {
+				return fmt.Errorf("%s: published port %v/%v is mapped to conflicting target ports %s and %s", p, port, protocol, other, target)
+			}
+			targets[key] = target
+		}
+	}
+	return nil
+}
+
+// expandPublishedPorts returns the host ports within a published port range, or published as is if it isn't a range
+func expandPublishedPorts(published string) []string {
+	start, end, err := nat.ParsePortRangeToInt(published)
+	if err != nil || start == end {
+		return []string{published}
+	}
+	ports := make([]string, 0, end-start+1)
+	for port := start; port <= end; port++ {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	return ports
+}
+
 func envFileIndexer(y any, _ tree.Path) (string, error) {
 	switch value := y.(type) {
 	case string:
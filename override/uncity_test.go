@@ -137,3 +137,49 @@ func assertUnicity(t *testing.T, before string, expected string) {
 	assert.NilError(t, err)
 	assert.DeepEqual(t, got, unmarshal(t, expected))
 }
+
+func Test_PortsConflict(t *testing.T) {
+	_, err := EnforceUnicity(unmarshal(t, `
+services:
+  test:
+    image: foo
+    ports:
+      - target: 80
+        published: "8080"
+        protocol: tcp
+      - target: 81
+        published: "8080"
+        protocol: tcp
+`))
+	assert.Error(t, err, "services.test.ports: published port 8080/tcp is mapped to conflicting target ports 80 and 81")
+}
+
+func Test_PortsRangeConflict(t *testing.T) {
+	_, err := EnforceUnicity(unmarshal(t, `
+services:
+  test:
+    image: foo
+    ports:
+      - target: 80
+        published: "8080-8085"
+        protocol: tcp
+      - target: 81
+        published: "8083"
+        protocol: tcp
+`))
+	assert.Error(t, err, "services.test.ports: published port 8083/tcp is mapped to conflicting target ports 80 and 81")
+
+	_, err = EnforceUnicity(unmarshal(t, `
+services:
+  test:
+    image: foo
+    ports:
+      - target: 80
+        published: "8080-8085"
+        protocol: tcp
+      - target: 81
+        published: "8086-8090"
+        protocol: tcp
+`))
+	assert.NilError(t, err)
+}
@@ -38,3 +38,9 @@ func (r *relativePathsResolver) maybeUnixPath(a any) (any, error) {
 	}
 	return p, nil
 }
+
+// IsAbs reports whether p is an absolute path, using either Unix or Windows syntax. This is relevant for paths
+// inside a container, which don't depend on the platform compose is running on
+func IsAbs(p string) bool {
+	return path.IsAbs(p) || isWindowsAbs(p)
+}
@@ -0,0 +1,52 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestIsWithin(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	assert.NilError(t, os.Mkdir(filepath.Join(base, "data"), 0o755))
+	assert.NilError(t, os.Symlink(outside, filepath.Join(base, "link")))
+
+	tests := []struct {
+		target string
+		within bool
+	}{
+		{target: base, within: true},
+		{target: filepath.Join(base, "data"), within: true},
+		{target: filepath.Join(base, "missing", "file"), within: true},
+		{target: filepath.Join(base, "data", "..", "data"), within: true},
+		{target: filepath.Join(base, ".."), within: false},
+		{target: filepath.Join(base, "data", "..", "..", "escape"), within: false},
+		{target: base + "-sibling", within: false},
+		{target: outside, within: false},
+		{target: filepath.Join(base, "link", "file"), within: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			assert.Equal(t, IsWithin(base, tt.target), tt.within)
+		})
+	}
+}
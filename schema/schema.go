@@ -128,6 +128,11 @@ func (err validationError) Error() string {
 	return fmt.Sprintf("%s %s", err.parent.Field(), description)
 }
 
+// Field returns the path to the invalid attribute
+func (err validationError) Field() string {
+	return err.parent.Field()
+}
+
 func getMostSpecificError(errors []gojsonschema.ResultError) validationError {
 	mostSpecificError := 0
 	for i, err := range errors {
@@ -46,6 +46,22 @@ var patternString = fmt.Sprintf(
 
 var defaultPattern = regexp.MustCompile(patternString)
 
+// NewPatternWithEscape returns a pattern which uses escape, rather than the default `$$` doubling, to prevent
+// the `$` delimiter from being interpolated. i.e. with a backtick escape character, "`$VAR" renders as "$VAR"
+func NewPatternWithEscape(escape rune) *regexp.Regexp {
+	if escape == '$' {
+		return defaultPattern
+	}
+	return regexp.MustCompile(fmt.Sprintf(
+		"%s(?P<%s>%s)|%s(?i:(?P<%s>%s)|{(?:(?P<%s>%s)}|(?P<%s>)))",
+		regexp.QuoteMeta(string(escape)), groupEscaped, delimiter,
+		delimiter,
+		groupNamed, substitutionNamed,
+		groupBraced, substitutionBraced,
+		groupInvalid,
+	))
+}
+
 // InvalidTemplateError is returned when a variable template is not in a valid
 // format
 type InvalidTemplateError struct {
@@ -88,9 +104,18 @@ type Config struct {
 	pattern         *regexp.Regexp
 	substituteFunc  SubstituteFunc
 	replacementFunc ReplacementFunc
+	missingFunc     func(string)
+	usedFunc        func(UsedVariable)
 	logging         bool
 }
 
+// used reports a variable consumed by the substitution, if a function has been set to collect those
+func (cfg *Config) used(v UsedVariable) {
+	if cfg.usedFunc != nil {
+		cfg.usedFunc(v)
+	}
+}
+
 type Option func(*Config)
 
 func WithPattern(pattern *regexp.Regexp) Option {
@@ -99,6 +124,13 @@ func WithPattern(pattern *regexp.Regexp) Option {
 	}
 }
 
+// WithEscapeCharacter configures the character used to escape the `$` delimiter, which defaults to `$` itself
+func WithEscapeCharacter(escape rune) Option {
+	return func(cfg *Config) {
+		cfg.pattern = NewPatternWithEscape(escape)
+	}
+}
+
 func WithSubstitutionFunction(subsFunc SubstituteFunc) Option {
 	return func(cfg *Config) {
 		cfg.substituteFunc = subsFunc
@@ -111,6 +143,14 @@ func WithReplacementFunction(replacementFunc ReplacementFunc) Option {
 	}
 }
 
+// WithMissingVariableFunction sets a function to be called with the name of each variable which is not set and has
+// no default value, instead of logging a warning
+func WithMissingVariableFunction(missingFunc func(name string)) Option {
+	return func(cfg *Config) {
+		cfg.missingFunc = missingFunc
+	}
+}
+
 func WithoutLogging(cfg *Config) {
 	cfg.logging = false
 }
@@ -118,8 +158,6 @@ func WithoutLogging(cfg *Config) {
 // SubstituteWithOptions substitute variables in the string with their values.
 // It accepts additional options such as a custom function or pattern.
 func SubstituteWithOptions(template string, mapping Mapping, options ...Option) (string, error) {
-	var returnErr error
-
 	cfg := &Config{
 		pattern:         defaultPattern,
 		replacementFunc: DefaultReplacementFunc,
@@ -128,7 +166,12 @@ func SubstituteWithOptions(template string, mapping Mapping, options ...Option)
 	for _, o := range options {
 		o(cfg)
 	}
+	return substituteWithConfig(template, mapping, cfg)
+}
 
+// substituteWithConfig substitutes variables in the string with their values, according to cfg
+func substituteWithConfig(template string, mapping Mapping, cfg *Config) (string, error) {
+	var returnErr error
 	result := cfg.pattern.ReplaceAllStringFunc(template, func(substring string) string {
 		replacement, err := cfg.replacementFunc(substring, mapping, cfg)
 		if err != nil {
@@ -151,6 +194,39 @@ func SubstituteWithOptions(template string, mapping Mapping, options ...Option)
 	return result, returnErr
 }
 
+// UsedVariable is a variable consumed by a substitution
+type UsedVariable struct {
+	Name string
+	// Set is true when the variable has a value in mapping, which can be empty
+	Set bool
+	// Defaulted is true when the substitution used the default or alternate value declared by the template, like
+	// `${VAR:-default}` with VAR unset or `${VAR:+alternate}` with VAR set, rather than the variable value
+	Defaulted bool
+}
+
+// SubstituteWithUsedVariables substitute variables in the string with their values, like SubstituteWithOptions, and
+// also returns the variables actually used by this substitution, in order of first use. Unlike ExtractVariables,
+// variables declared in default or alternate values are only reported when the template selects this value.
+// Variables handled by a custom substitution function are not reported.
+func SubstituteWithUsedVariables(template string, mapping Mapping, options ...Option) (string, []UsedVariable, error) {
+	var used []UsedVariable
+	collect := func(cfg *Config) {
+		cfg.usedFunc = func(v UsedVariable) {
+			for _, u := range used {
+				if u == v {
+					return
+				}
+			}
+			used = append(used, v)
+		}
+	}
+	result, err := SubstituteWithOptions(template, mapping, append(options[:len(options):len(options)], collect)...)
+	if err != nil {
+		return "", nil, err
+	}
+	return result, used, nil
+}
+
 func DefaultReplacementFunc(substring string, mapping Mapping, cfg *Config) (string, error) {
 	value, _, err := DefaultReplacementAppliedFunc(substring, mapping, cfg)
 	return value, err
@@ -160,7 +236,7 @@ func DefaultReplacementAppliedFunc(substring string, mapping Mapping, cfg *Confi
 	pattern := cfg.pattern
 	subsFunc := cfg.substituteFunc
 	if subsFunc == nil {
-		_, subsFunc = getSubstitutionFunctionForTemplate(substring)
+		_, subsFunc = getSubstitutionFunctionForTemplateWith(substring, cfg)
 	}
 
 	closingBraceIndex := getFirstBraceClosingIndex(substring)
@@ -193,7 +269,7 @@ func DefaultReplacementAppliedFunc(substring string, mapping Mapping, cfg *Confi
 			return "", false, err
 		}
 		if applied {
-			interpolatedNested, err := SubstituteWith(rest, mapping, pattern)
+			interpolatedNested, err := substituteWithConfig(rest, mapping, cfg)
 			if err != nil {
 				return "", false, err
 			}
@@ -202,7 +278,12 @@ func DefaultReplacementAppliedFunc(substring string, mapping Mapping, cfg *Confi
 	}
 
 	value, ok := mapping(substitution)
-	if !ok && cfg.logging {
+	cfg.used(UsedVariable{Name: substitution, Set: ok})
+	switch {
+	case ok:
+	case cfg.missingFunc != nil:
+		cfg.missingFunc(substitution)
+	case cfg.logging:
 		logrus.Warnf("The %q variable is not set. Defaulting to a blank string.", substitution)
 	}
 
@@ -223,16 +304,26 @@ func SubstituteWith(template string, mapping Mapping, pattern *regexp.Regexp, su
 }
 
 func getSubstitutionFunctionForTemplate(template string) (string, SubstituteFunc) {
+	return getSubstitutionFunctionForTemplateWith(template, &Config{
+		pattern:         defaultPattern,
+		replacementFunc: DefaultReplacementFunc,
+		logging:         true,
+	})
+}
+
+// getSubstitutionFunctionForTemplateWith returns the first separator used by template and the matching
+// SubstituteFunc, which substitutes nested variables according to cfg
+func getSubstitutionFunctionForTemplateWith(template string, cfg *Config) (string, SubstituteFunc) {
 	interpolationMapping := []struct {
 		string
 		SubstituteFunc
 	}{
-		{":?", requiredErrorWhenEmptyOrUnset},
-		{"?", requiredErrorWhenUnset},
-		{":-", defaultWhenEmptyOrUnset},
-		{"-", defaultWhenUnset},
-		{":+", defaultWhenNotEmpty},
-		{"+", defaultWhenSet},
+		{":?", requiredErrorWhenEmptyOrUnset(cfg)},
+		{"?", requiredErrorWhenUnset(cfg)},
+		{":-", defaultWhenEmptyOrUnset(cfg)},
+		{"-", defaultWhenUnset(cfg)},
+		{":+", defaultWhenNotEmpty(cfg)},
+		{"+", defaultWhenSet(cfg)},
 	}
 	sort.Slice(interpolationMapping, func(i, j int) bool {
 		idxI := strings.Index(template, interpolationMapping[i].string)
@@ -368,32 +459,44 @@ func extractVariable(value interface{}, pattern *regexp.Regexp) ([]Variable, boo
 }
 
 // Soft default (fall back if unset or empty)
-func defaultWhenEmptyOrUnset(substitution string, mapping Mapping) (string, bool, error) {
-	return withDefaultWhenAbsence(substitution, mapping, true)
+func defaultWhenEmptyOrUnset(cfg *Config) SubstituteFunc {
+	return func(substitution string, mapping Mapping) (string, bool, error) {
+		return withDefaultWhenAbsence(substitution, mapping, true, cfg)
+	}
 }
 
 // Hard default (fall back if-and-only-if empty)
-func defaultWhenUnset(substitution string, mapping Mapping) (string, bool, error) {
-	return withDefaultWhenAbsence(substitution, mapping, false)
+func defaultWhenUnset(cfg *Config) SubstituteFunc {
+	return func(substitution string, mapping Mapping) (string, bool, error) {
+		return withDefaultWhenAbsence(substitution, mapping, false, cfg)
+	}
 }
 
-func defaultWhenNotEmpty(substitution string, mapping Mapping) (string, bool, error) {
-	return withDefaultWhenPresence(substitution, mapping, true)
+func defaultWhenNotEmpty(cfg *Config) SubstituteFunc {
+	return func(substitution string, mapping Mapping) (string, bool, error) {
+		return withDefaultWhenPresence(substitution, mapping, true, cfg)
+	}
 }
 
-func defaultWhenSet(substitution string, mapping Mapping) (string, bool, error) {
-	return withDefaultWhenPresence(substitution, mapping, false)
+func defaultWhenSet(cfg *Config) SubstituteFunc {
+	return func(substitution string, mapping Mapping) (string, bool, error) {
+		return withDefaultWhenPresence(substitution, mapping, false, cfg)
+	}
 }
 
-func requiredErrorWhenEmptyOrUnset(substitution string, mapping Mapping) (string, bool, error) {
-	return withRequired(substitution, mapping, ":?", func(v string) bool { return v != "" })
+func requiredErrorWhenEmptyOrUnset(cfg *Config) SubstituteFunc {
+	return func(substitution string, mapping Mapping) (string, bool, error) {
+		return withRequired(substitution, mapping, ":?", func(v string) bool { return v != "" }, cfg)
+	}
 }
 
-func requiredErrorWhenUnset(substitution string, mapping Mapping) (string, bool, error) {
-	return withRequired(substitution, mapping, "?", func(_ string) bool { return true })
+func requiredErrorWhenUnset(cfg *Config) SubstituteFunc {
+	return func(substitution string, mapping Mapping) (string, bool, error) {
+		return withRequired(substitution, mapping, "?", func(_ string) bool { return true }, cfg)
+	}
 }
 
-func withDefaultWhenPresence(substitution string, mapping Mapping, notEmpty bool) (string, bool, error) {
+func withDefaultWhenPresence(substitution string, mapping Mapping, notEmpty bool, cfg *Config) (string, bool, error) {
 	sep := "+"
 	if notEmpty {
 		sep = ":+"
@@ -402,18 +505,21 @@ func withDefaultWhenPresence(substitution string, mapping Mapping, notEmpty bool
 		return "", false, nil
 	}
 	name, defaultValue := partition(substitution, sep)
-	defaultValue, err := Substitute(defaultValue, mapping)
-	if err != nil {
-		return "", false, err
-	}
 	value, ok := mapping(name)
 	if ok && (!notEmpty || (notEmpty && value != "")) {
+		cfg.used(UsedVariable{Name: name, Set: ok, Defaulted: true})
+		// the alternate value is only evaluated when used, as a shell does
+		defaultValue, err := substituteWithConfig(defaultValue, mapping, cfg)
+		if err != nil {
+			return "", false, err
+		}
 		return defaultValue, true, nil
 	}
+	cfg.used(UsedVariable{Name: name, Set: ok})
 	return value, true, nil
 }
 
-func withDefaultWhenAbsence(substitution string, mapping Mapping, emptyOrUnset bool) (string, bool, error) {
+func withDefaultWhenAbsence(substitution string, mapping Mapping, emptyOrUnset bool, cfg *Config) (string, bool, error) {
 	sep := "-"
 	if emptyOrUnset {
 		sep = ":-"
@@ -422,27 +528,34 @@ func withDefaultWhenAbsence(substitution string, mapping Mapping, emptyOrUnset b
 		return "", false, nil
 	}
 	name, defaultValue := partition(substitution, sep)
-	defaultValue, err := Substitute(defaultValue, mapping)
-	if err != nil {
-		return "", false, err
-	}
 	value, ok := mapping(name)
 	if !ok || (emptyOrUnset && value == "") {
+		cfg.used(UsedVariable{Name: name, Set: ok, Defaulted: true})
+		// the default value is only evaluated when used, as a shell does
+		defaultValue, err := substituteWithConfig(defaultValue, mapping, cfg)
+		if err != nil {
+			return "", false, err
+		}
 		return defaultValue, true, nil
 	}
+	cfg.used(UsedVariable{Name: name, Set: ok})
 	return value, true, nil
 }
 
-func withRequired(substitution string, mapping Mapping, sep string, valid func(string) bool) (string, bool, error) {
+func withRequired(substitution string, mapping Mapping, sep string, valid func(string) bool, cfg *Config) (string, bool, error) {
 	if !strings.Contains(substitution, sep) {
 		return "", false, nil
 	}
 	name, errorMessage := partition(substitution, sep)
-	errorMessage, err := Substitute(errorMessage, mapping)
+	// the error message is always evaluated, but its variables are not used by the substitution
+	message := *cfg
+	message.usedFunc = nil
+	errorMessage, err := substituteWithConfig(errorMessage, mapping, &message)
 	if err != nil {
 		return "", false, err
 	}
 	value, ok := mapping(name)
+	cfg.used(UsedVariable{Name: name, Set: ok})
 	if !ok || !valid(value) {
 		return "", true, &MissingRequiredError{
 			Reason:   errorMessage,
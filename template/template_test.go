@@ -36,9 +36,49 @@ func defaultMapping(name string) (string, bool) {
 }
 
 func TestEscaped(t *testing.T) {
-	result, err := Substitute("$${foo}", defaultMapping)
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{template: "$${foo}", expected: "${foo}"},
+		{template: "pa$$word", expected: "pa$word"},
+		{template: "$${NOT_A_VAR}", expected: "${NOT_A_VAR}"},
+		{template: "trailing $$", expected: "trailing $"},
+		{template: "$$$$", expected: "$$"},
+		{template: "$${FOO}$FOO", expected: "${FOO}first"},
+		{template: "$$$FOO", expected: "$first"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			result, err := Substitute(tc.template, defaultMapping)
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(tc.expected, result))
+		})
+	}
+}
+
+func TestEscapedWithCustomCharacter(t *testing.T) {
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{template: "`$FOO", expected: "$FOO"},
+		{template: "`${FOO}", expected: "${FOO}"},
+		{template: "`$FOO $FOO", expected: "$FOO first"},
+		{template: "`FOO ${FOO}", expected: "`FOO first"},
+		{template: "$$FOO", expected: "$first"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			result, err := SubstituteWithOptions(tc.template, defaultMapping, WithEscapeCharacter('`'))
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(tc.expected, result))
+		})
+	}
+
+	result, err := SubstituteWithOptions("$${FOO}", defaultMapping, WithEscapeCharacter('$'))
 	assert.NilError(t, err)
-	assert.Check(t, is.Equal("${foo}", result))
+	assert.Check(t, is.Equal("${FOO}", result))
 }
 
 func TestSubstituteNoMatch(t *testing.T) {
@@ -263,6 +303,26 @@ func TestDefaultsWithNestedExpansion(t *testing.T) {
 			template: "ok ${BAR+$FOO ${FOO:+second}}",
 			expected: "ok first second",
 		},
+		{
+			template: "${UNSET_VAR:-${BAR:-${FOO:-docker.io}}}/app",
+			expected: "first/app",
+		},
+		{
+			template: "${UNSET_VAR:-${BAR:-${OTHER_UNSET:-docker.io}}}/app",
+			expected: "docker.io/app",
+		},
+		{
+			template: "ok ${UNSET_VAR:-a-${BAR:-b-${OTHER_UNSET:-c}-b}-a}",
+			expected: "ok a-b-c-b-a",
+		},
+		{
+			template: "ok ${UNSET_VAR:-${BAR?bar is required}}",
+			expected: "ok ",
+		},
+		{
+			template: "ok ${UNSET_VAR:-${BAR:-${FOO:?foo is required}}}",
+			expected: "ok first",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -319,6 +379,14 @@ func TestMandatoryVariableErrorsWithNestedExpansion(t *testing.T) {
 			template:      "not ok ${UNSET_VAR?Mandatory Variable ${FOO}}",
 			expectedError: "required variable UNSET_VAR is missing a value: Mandatory Variable first",
 		},
+		{
+			template:      "not ok ${UNSET_VAR:-${BAR:?Mandatory Variable Empty}}",
+			expectedError: "required variable BAR is missing a value: Mandatory Variable Empty",
+		},
+		{
+			template:      "not ok ${UNSET_VAR:-${BAR:-${OTHER_UNSET:?Mandatory Variable Unset}}}",
+			expectedError: "required variable OTHER_UNSET is missing a value: Mandatory Variable Unset",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -630,3 +698,89 @@ func TestSubstitutionFunctionChoice(t *testing.T) {
 		})
 	}
 }
+
+func TestSubstituteWithUsedVariables(t *testing.T) {
+	testCases := []struct {
+		template string
+		expected string
+		used     []UsedVariable
+	}{
+		{template: "$FOO", expected: "first", used: []UsedVariable{{Name: "FOO", Set: true}}},
+		{template: "${FOO} $$BAR", expected: "first $BAR", used: []UsedVariable{{Name: "FOO", Set: true}}},
+		{template: "${UNSET}", expected: "", used: []UsedVariable{{Name: "UNSET"}}},
+		{
+			template: "${UNSET:-$FOO}",
+			expected: "first",
+			used:     []UsedVariable{{Name: "UNSET", Defaulted: true}, {Name: "FOO", Set: true}},
+		},
+		{
+			template: "${FOO:-$BAR}",
+			expected: "first",
+			used:     []UsedVariable{{Name: "FOO", Set: true}},
+		},
+		{
+			template: "${BAR-default}",
+			expected: "",
+			used:     []UsedVariable{{Name: "BAR", Set: true}},
+		},
+		{
+			template: "${BAR:-default}",
+			expected: "default",
+			used:     []UsedVariable{{Name: "BAR", Set: true, Defaulted: true}},
+		},
+		{
+			template: "${FOO:+${UNSET:-alternate}} ${BAR:+$FOO}",
+			expected: "alternate ",
+			used: []UsedVariable{
+				{Name: "FOO", Set: true, Defaulted: true},
+				{Name: "UNSET", Defaulted: true},
+				{Name: "BAR", Set: true},
+			},
+		},
+		{
+			template: "${FOO}-${FOO:-x}-${FOO}",
+			expected: "first-first-first",
+			used:     []UsedVariable{{Name: "FOO", Set: true}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			result, used, err := SubstituteWithUsedVariables(tc.template, defaultMapping)
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(tc.expected, result))
+			assert.Check(t, is.DeepEqual(tc.used, used))
+		})
+	}
+
+	_, _, err := SubstituteWithUsedVariables("${UNSET:?required}", defaultMapping)
+	assert.Error(t, err, "required variable UNSET is missing a value: required")
+}
+
+func TestMissingVariableFunctionNested(t *testing.T) {
+	var missing []string
+	onMissing := WithMissingVariableFunction(func(name string) {
+		missing = append(missing, name)
+	})
+	result, err := SubstituteWithOptions("${UNSET:-${FOO}} ${UNSET:-${NESTED}} ${FOO:+${PRESENT}}", defaultMapping, onMissing)
+	assert.NilError(t, err)
+	assert.Equal(t, result, "first  ")
+	assert.DeepEqual(t, missing, []string{"NESTED", "PRESENT"})
+}
+
+func TestUnusedDefaultIsNotEvaluated(t *testing.T) {
+	result, err := Substitute("${FOO:-${UNSET:?msg}} ${UNSET:+${UNSET:?msg}}", defaultMapping)
+	assert.NilError(t, err)
+	assert.Equal(t, result, "first ")
+
+	_, err = Substitute("${UNSET:-${UNSET:?msg}}", defaultMapping)
+	assert.ErrorContains(t, err, "required variable UNSET is missing a value: msg")
+
+	var missing []string
+	onMissing := WithMissingVariableFunction(func(name string) {
+		missing = append(missing, name)
+	})
+	result, err = SubstituteWithOptions("${FOO:-${UNUSED}} ${FOO:+${USED}}", defaultMapping, onMissing)
+	assert.NilError(t, err)
+	assert.Equal(t, result, "first ")
+	assert.DeepEqual(t, missing, []string{"USED"})
+}
@@ -31,6 +31,7 @@ func init() {
 	transformers["services.*.depends_on"] = transformDependsOn
 	transformers["services.*.env_file"] = transformEnvFile
 	transformers["services.*.extends"] = transformExtends
+	transformers["services.*.gpus"] = transformGpus
 	transformers["services.*.networks"] = transformServiceNetworks
 	transformers["services.*.volumes.*"] = transformVolumeMount
 	transformers["services.*.secrets.*"] = transformFileMount
@@ -40,6 +41,7 @@ func init() {
 	transformers["services.*.build.ssh"] = transformSSH
 	transformers["services.*.ulimits.*"] = transformUlimits
 	transformers["services.*.build.ulimits.*"] = transformUlimits
+	transformers["services.*.deploy.resources.*.cpus"] = transformCPUs
 	transformers["volumes.*"] = transformMaybeExternal
 	transformers["networks.*"] = transformMaybeExternal
 	transformers["secrets.*"] = transformMaybeExternal
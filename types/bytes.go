@@ -18,6 +18,7 @@ package types
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/docker/go-units"
 )
@@ -40,9 +41,29 @@ func (u *UnitBytes) DecodeMapstructure(value interface{}) error {
 	case int:
 		*u = UnitBytes(v)
 	case string:
-		b, err := units.RAMInBytes(fmt.Sprint(value))
+		b, err := units.RAMInBytes(trimBinaryPrefix(v))
 		*u = UnitBytes(b)
 		return err
 	}
 	return nil
 }
+
+// trimBinaryPrefix converts IEC suffixes (`Ki`, `Mi`, `GiB`, ...) to the equivalent docker units suffix, as
+// units.RAMInBytes already computes sizes using binary (1024-based) multiples
+func trimBinaryPrefix(size string) string {
+	lower := strings.ToLower(size)
+	for _, suffix := range []string{"ib", "i"} {
+		if !strings.HasSuffix(lower, suffix) {
+			continue
+		}
+		trimmed := size[:len(size)-len(suffix)]
+		if trimmed == "" || !strings.ContainsAny(trimmed[len(trimmed)-1:], "kKmMgGtTpP") {
+			return size
+		}
+		if suffix == "ib" {
+			return trimmed + "b"
+		}
+		return trimmed
+	}
+	return size
+}
@@ -16,7 +16,12 @@
 
 package types
 
-import "github.com/mattn/go-shellwords"
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+)
 
 // ShellCommand is a string or list of string args.
 //
@@ -76,11 +81,32 @@ func (s *ShellCommand) DecodeMapstructure(value interface{}) error {
 		}
 		*s = cmd
 	case []interface{}:
-		cmd := make([]string, len(v))
-		for i, s := range v {
-			cmd[i] = s.(string)
+		cmd, err := toStrings(v)
+		if err != nil {
+			return err
 		}
 		*s = cmd
 	}
 	return nil
 }
+
+// safeShellWord matches arguments which don't need to be quoted for a shell
+var safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// String renders command as a shell string, quoting arguments as required so that parsing it back as a
+// shell command produces the same arguments
+func (s ShellCommand) String() string {
+	quoted := make([]string, len(s))
+	for i, arg := range s {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote wraps arg with single quotes if it contains characters with a special meaning for a shell
+func shellQuote(arg string) string {
+	if safeShellWord.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -17,10 +17,12 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
 	"runtime"
 	"strings"
 
+	"github.com/compose-spec/compose-go/v2/utils"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -133,3 +135,31 @@ func (e Extensions) Get(name string, target interface{}) (bool, error) {
 	}
 	return false, nil
 }
+
+// marshalJSONWithExtensions renders v as a JSON object and appends extensions as additional fields, as encoding/json
+// doesn't support inlined maps (see https://github.com/golang/go/issues/6213)
+func marshalJSONWithExtensions(v interface{}, extensions Extensions) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(extensions) == 0 {
+		return b, err
+	}
+	buf := bytes.NewBuffer(b[:len(b)-1])
+	for i, k := range utils.MapKeys(extensions) {
+		if i > 0 || len(b) > 2 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(extensions[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
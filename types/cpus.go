@@ -0,0 +1,34 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// CPUs returns the number of CPUs set by NanoCPUs as a fractional value (e.g. 0.5 for half a CPU), or 0 if unset
+func (r Resource) CPUs() (float32, error) {
+	if r.NanoCPUs == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(r.NanoCPUs, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpus value %q", r.NanoCPUs)
+	}
+	return float32(f), nil
+}
@@ -22,22 +22,44 @@ import (
 	"strings"
 )
 
+// DeviceRequest is a request for devices, typically GPUs, to be made available to the service containers
 type DeviceRequest struct {
 	Capabilities []string    `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`
 	Driver       string      `yaml:"driver,omitempty" json:"driver,omitempty"`
 	Count        DeviceCount `yaml:"count,omitempty" json:"count,omitempty"`
 	IDs          []string    `yaml:"device_ids,omitempty" json:"device_ids,omitempty"`
+	Options      Mapping     `yaml:"options,omitempty" json:"options,omitempty"`
 }
 
+// DeviceCount is the number of devices requested, -1 meaning `all`
 type DeviceCount int64
 
+// AllDevices is the DeviceCount value used to request all available devices
+const AllDevices DeviceCount = -1
+
+// MarshalYAML makes DeviceCount implement yaml.Marshaller
+func (c DeviceCount) MarshalYAML() (interface{}, error) {
+	if c == AllDevices {
+		return "all", nil
+	}
+	return int64(c), nil
+}
+
+// MarshalJSON makes DeviceCount implement json.Marshaler
+func (c DeviceCount) MarshalJSON() ([]byte, error) {
+	if c == AllDevices {
+		return []byte(`"all"`), nil
+	}
+	return []byte(strconv.FormatInt(int64(c), 10)), nil
+}
+
 func (c *DeviceCount) DecodeMapstructure(value interface{}) error {
 	switch v := value.(type) {
 	case int:
 		*c = DeviceCount(v)
 	case string:
 		if strings.ToLower(v) == "all" {
-			*c = -1
+			*c = AllDevices
 			return nil
 		}
 		i, err := strconv.ParseInt(v, 10, 64)
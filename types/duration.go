@@ -21,21 +21,35 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-// Duration is a thin wrapper around time.Duration with improved JSON marshalling
+// Duration is a thin wrapper around time.Duration with improved JSON marshalling.
+// Durations are parsed using Go duration syntax, like `30s` or `1m30s`, whatever the source (compose file, JSON or
+// YAML). They are written in the canonical form produced by time.Duration.String, so `90s` is written back as
+// `1m30s` and zero as `0s`. Optional attributes use a *Duration, so that zero and unset can be distinguished.
 type Duration time.Duration
 
 func (d Duration) String() string {
 	return time.Duration(d).String()
 }
 
-func (d *Duration) DecodeMapstructure(value interface{}) error {
+// parseDuration is the single code path used to decode a Duration
+func parseDuration(value interface{}) (Duration, error) {
 	v, err := time.ParseDuration(fmt.Sprint(value))
+	if err != nil {
+		return 0, err
+	}
+	return Duration(v), nil
+}
+
+func (d *Duration) DecodeMapstructure(value interface{}) error {
+	v, err := parseDuration(value)
 	if err != nil {
 		return err
 	}
-	*d = Duration(v)
+	*d = v
 	return nil
 }
 
@@ -50,11 +64,20 @@ func (d Duration) MarshalYAML() (interface{}, error) {
 }
 
 func (d *Duration) UnmarshalJSON(b []byte) error {
-	s := strings.Trim(string(b), "\"")
-	timeDuration, err := time.ParseDuration(s)
+	v, err := parseDuration(strings.Trim(string(b), "\""))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// UnmarshalYAML makes Duration implement yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	v, err := parseDuration(value.Value)
 	if err != nil {
 		return err
 	}
-	*d = Duration(timeDuration)
+	*d = v
 	return nil
 }
@@ -0,0 +1,124 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// Equal reports whether p and other describe the same compose model.
+//
+// Comparison relies on the JSON representation of the projects, so attributes which are not part of the compose
+// model (WorkingDir, ComposeFiles, Environment, Profiles and DisabledServices) are ignored. Mappings are compared
+// regardless of their order. Sequences where order is not significant are sorted before comparison:
+// cap_add, cap_drop, configs, devices, device_cgroup_rules, dns, dns_opt, dns_search, expose, external_links,
+// group_add, links, ports, profiles, secrets, security_opt, tmpfs, volumes and volumes_from.
+// Other sequences, typically command, entrypoint, env_file or healthcheck test, are order-significant.
+func (p *Project) Equal(other *Project) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	a, err := p.canonicalJSON()
+	if err != nil {
+		return false
+	}
+	b, err := other.canonicalJSON()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
+
+// Hash returns a hex encoded SHA-256 digest of the project model, to be used as a cache key.
+//
+// The digest is computed over the same canonical representation used by Equal, so the same attributes are ignored
+// and the same sequences are sorted. To keep the digest portable across checkouts, paths are made relative to
+// WorkingDir before hashing, as RelativePaths does: build context and additional_contexts, bind mount sources,
+// env_file and label_file, extends file, develop watch paths, volume device driver option, and configs and secrets
+// file. Paths outside WorkingDir are hashed as-is.
+func (p *Project) Hash() (string, error) {
+	c := p
+	if p.WorkingDir != "" {
+		c = p.RelativePaths(p.WorkingDir)
+	}
+	b, err := c.canonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON returns the JSON representation of the project, with sequences where order is not significant sorted
+func (p *Project) canonicalJSON() ([]byte, error) {
+	c := p.DeepCopy()
+	for name, s := range c.Services {
+		sortByJSON(s.CapAdd)
+		sortByJSON(s.CapDrop)
+		sortByJSON(s.Configs)
+		sortByJSON(s.Devices)
+		sortByJSON(s.DeviceCgroupRules)
+		sortByJSON(s.DNS)
+		sortByJSON(s.DNSOpts)
+		sortByJSON(s.DNSSearch)
+		sortByJSON(s.Expose)
+		sortByJSON(s.ExternalLinks)
+		sortByJSON(s.GroupAdd)
+		sortByJSON(s.Links)
+		sortByJSON(s.Ports)
+		sortByJSON(s.Profiles)
+		sortByJSON(s.Secrets)
+		sortByJSON(s.SecurityOpt)
+		sortByJSON(s.Tmpfs)
+		sortByJSON(s.Volumes)
+		sortByJSON(s.VolumesFrom)
+		c.Services[name] = s
+	}
+	return json.Marshal(c)
+}
+
+// sortByJSON sorts a slice in place, comparing the JSON representation of its elements
+func sortByJSON[T any](s []T) {
+	keys := make([]string, len(s))
+	for i, e := range s {
+		b, _ := json.Marshal(e)
+		keys[i] = string(b)
+	}
+	sort.Sort(byKeys[T]{keys: keys, values: s})
+}
+
+type byKeys[T any] struct {
+	keys   []string
+	values []T
+}
+
+func (b byKeys[T]) Len() int {
+	return len(b.keys)
+}
+
+func (b byKeys[T]) Less(i, j int) bool {
+	return b.keys[i] < b.keys[j]
+}
+
+func (b byKeys[T]) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+}
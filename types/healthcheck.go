@@ -33,13 +33,42 @@ type HealthCheckConfig struct {
 	Extensions Extensions `yaml:"#extensions,inline,omitempty" json:"-"`
 }
 
-// HealthCheckTest is the command run to test the health of a service
+// HealthCheckTest is the command run to test the health of a service. Its first element declares the form of
+// the command: `CMD` for exec form, followed by the command arguments, `CMD-SHELL` for shell form, followed by the
+// command to be run by the container default shell, or `NONE` to disable the healthcheck set by the image.
+// The short syntax, a plain string, is decoded as shell form.
 type HealthCheckTest []string
 
+const (
+	// HealthCheckExec is the form of a test command run as exec form
+	HealthCheckExec = "CMD"
+	// HealthCheckShell is the form of a test command run by the container default shell
+	HealthCheckShell = "CMD-SHELL"
+	// HealthCheckNone is the form of a test disabling the image healthcheck
+	HealthCheckNone = "NONE"
+)
+
+// Form returns the form of the test command, one of HealthCheckExec, HealthCheckShell or HealthCheckNone, or an
+// empty string if test isn't set
+func (l HealthCheckTest) Form() string {
+	if len(l) == 0 {
+		return ""
+	}
+	return l[0]
+}
+
+// Command returns the test command, without the leading form
+func (l HealthCheckTest) Command() []string {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[1:]
+}
+
 func (l *HealthCheckTest) DecodeMapstructure(value interface{}) error {
 	switch v := value.(type) {
 	case string:
-		*l = []string{"CMD-SHELL", v}
+		*l = []string{HealthCheckShell, v}
 	case []interface{}:
 		seq := make([]string, len(v))
 		for i, e := range v {
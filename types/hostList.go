@@ -23,6 +23,9 @@ import (
 	"strings"
 )
 
+// HostGateway is a special value for extra_hosts, resolved by the engine to the host's gateway IP
+const HostGateway = "host-gateway"
+
 // HostsList is a list of colon-separated host-ip mappings
 type HostsList map[string][]string
 
@@ -50,6 +50,19 @@ func (m MappingWithEquals) OverrideBy(other MappingWithEquals) MappingWithEquals
 	return m
 }
 
+// OverrideWith returns a new MappingWithEquals with values from other taking precedence. Unlike OverrideBy, m is
+// left unchanged. A key without value in other overrides a value set by m, so it gets inherited from the environment
+func (m MappingWithEquals) OverrideWith(other MappingWithEquals) MappingWithEquals {
+	merged := make(MappingWithEquals, len(m)+len(other))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
 // Resolve update a MappingWithEquals for keys without value (`key`, but not `key=`)
 func (m MappingWithEquals) Resolve(lookupFn func(string) (string, bool)) MappingWithEquals {
 	for k, v := range m {
@@ -62,6 +75,26 @@ func (m MappingWithEquals) Resolve(lookupFn func(string) (string, bool)) Mapping
 	return m
 }
 
+// ToMapping returns a Mapping with values set for all keys. Keys without value are resolved using lookupFn,
+// and excluded if lookupFn can't resolve them. Unlike Resolve, original MappingWithEquals is left unchanged,
+// so it can be resolved again against another environment.
+func (m MappingWithEquals) ToMapping(lookupFn func(string) (string, bool)) Mapping {
+	mapping := make(Mapping, len(m))
+	for k, v := range m {
+		if v != nil {
+			mapping[k] = *v
+			continue
+		}
+		if lookupFn == nil {
+			continue
+		}
+		if value, ok := lookupFn(k); ok {
+			mapping[k] = value
+		}
+	}
+	return mapping
+}
+
 // RemoveEmpty excludes keys that are not associated with a value
 func (m MappingWithEquals) RemoveEmpty() MappingWithEquals {
 	for k, v := range m {
@@ -176,6 +209,15 @@ func (m Mapping) Merge(o Mapping) Mapping {
 	return m
 }
 
+// OverrideWith returns a new Mapping with values from other taking precedence. Unlike Merge, m is left unchanged
+func (m Mapping) OverrideWith(other Mapping) Mapping {
+	merged := m.Clone()
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
 func (m *Mapping) DecodeMapstructure(value interface{}) error {
 	switch v := value.(type) {
 	case map[string]interface{}:
@@ -0,0 +1,360 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/tree"
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalOption configures Project serialization
+type MarshalOption func(*marshalOptions)
+
+type marshalOptions struct {
+	sorted       []tree.Path
+	omitDefaults bool
+	shortSyntax  bool
+}
+
+// WithSortedEnvironment sorts services environment variables by name, using plain lexical order, so that
+// serialized projects produce stable and predictable diffs. Other attributes, like command, are left untouched.
+func WithSortedEnvironment() MarshalOption {
+	return func(o *marshalOptions) {
+		o.sorted = append(o.sorted, tree.NewPath("services", tree.PathMatchAll, "environment"))
+	}
+}
+
+// WithSortedLabels sorts labels set on services, builds and top-level resources by name, using plain lexical order
+func WithSortedLabels() MarshalOption {
+	return func(o *marshalOptions) {
+		o.sorted = append(o.sorted,
+			tree.NewPath("services", tree.PathMatchAll, "labels"),
+			tree.NewPath("services", tree.PathMatchAll, "build", "labels"),
+			tree.NewPath("networks", tree.PathMatchAll, "labels"),
+			tree.NewPath("volumes", tree.PathMatchAll, "labels"),
+			tree.NewPath("secrets", tree.PathMatchAll, "labels"),
+			tree.NewPath("configs", tree.PathMatchAll, "labels"),
+		)
+	}
+}
+
+// WithoutDefaultValues omits attributes set to their default value, like the implicit `Dockerfile`, ports
+// `ingress` mode and `tcp` protocol, or resource names derived from the project name, so that serialized projects
+// are closer to what users write. The output still loads as the same project.
+func WithoutDefaultValues() MarshalOption {
+	return func(o *marshalOptions) {
+		o.omitDefaults = true
+	}
+}
+
+// WithShortSyntax renders build, depends_on, ports, volumes, secrets and configs using their short syntax when
+// this doesn't lose any information. Combined with WithoutDefaultValues, it produces a compact output suited for
+// diffs, rather than the canonical long syntax.
+func WithShortSyntax() MarshalOption {
+	return func(o *marshalOptions) {
+		o.shortSyntax = true
+	}
+}
+
+type nodeTransformer func(node *yaml.Node, p tree.Path) *yaml.Node
+
+// defaultValuesRemovers returns the transformers removing attributes set to their default value
+func defaultValuesRemovers(project string) map[tree.Path]nodeTransformer {
+	resourceName := func(node *yaml.Node, p tree.Path) *yaml.Node {
+		key := p.Last()
+		if external := nodeValue(node, "external"); external != nil && external.Value == "true" {
+			removeDefault(node, "name", key)
+		} else {
+			removeDefault(node, "name", fmt.Sprintf("%s_%s", project, key))
+		}
+		return node
+	}
+	return map[tree.Path]nodeTransformer{
+		"services.*.build": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+			removeDefault(node, "dockerfile", "Dockerfile")
+			return node
+		},
+		"services.*.depends_on.*": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+			removeDefault(node, "required", "true")
+			return node
+		},
+		"services.*.ports.[]": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+			removeDefault(node, "mode", "ingress")
+			removeDefault(node, "protocol", "tcp")
+			return node
+		},
+		"services.*.volumes.[]": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+			for _, key := range []string{"bind", "volume", "tmpfs", "image"} {
+				if v := nodeValue(node, key); v != nil && v.Kind == yaml.MappingNode && len(v.Content) == 0 {
+					removeKey(node, key)
+				}
+			}
+			return node
+		},
+		"services.*.secrets.[]": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+			if source := nodeValue(node, "source"); source != nil {
+				removeDefault(node, "target", "/run/secrets/"+source.Value)
+			}
+			return node
+		},
+		"services.*.configs.[]": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+			if source := nodeValue(node, "source"); source != nil {
+				removeDefault(node, "target", "/"+source.Value)
+			}
+			return node
+		},
+		"networks.*": resourceName,
+		"volumes.*":  resourceName,
+		"secrets.*":  resourceName,
+		"configs.*":  resourceName,
+	}
+}
+
+// shortSyntaxConverters returns the transformers rendering attributes using short syntax, when this is lossless
+var shortSyntaxConverters = map[tree.Path]nodeTransformer{
+	"services.*.build": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+		if !hasOnlyKeys(node, map[string]string{"context": "", "dockerfile": "Dockerfile"}) {
+			return node
+		}
+		if context := nodeValue(node, "context"); context != nil {
+			return stringNode(context.Value)
+		}
+		return node
+	},
+	"services.*.depends_on": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+		if node.Kind != yaml.MappingNode {
+			return node
+		}
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !hasOnlyKeys(node.Content[i+1], map[string]string{"condition": ServiceConditionStarted, "required": "true"}) {
+				return node
+			}
+			seq.Content = append(seq.Content, stringNode(node.Content[i].Value))
+		}
+		return seq
+	},
+	"services.*.ports.[]": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+		if !hasOnlyKeys(node, map[string]string{"mode": "ingress", "host_ip": "", "target": "", "published": "", "protocol": ""}) {
+			return node
+		}
+		target := nodeValue(node, "target")
+		if target == nil {
+			return node
+		}
+		port := target.Value
+		if published := nodeValue(node, "published"); published != nil {
+			port = published.Value + ":" + port
+		}
+		if host := nodeValue(node, "host_ip"); host != nil {
+			ip := host.Value
+			if strings.Contains(ip, ":") {
+				ip = "[" + ip + "]"
+			}
+			if nodeValue(node, "published") == nil {
+				port = ":" + port
+			}
+			port = ip + ":" + port
+		}
+		if protocol := nodeValue(node, "protocol"); protocol != nil && protocol.Value != "tcp" {
+			port = port + "/" + protocol.Value
+		}
+		// quote ports, which YAML 1.1 parsers could read as sexagesimal numbers
+		short := stringNode(port)
+		short.Style = yaml.DoubleQuotedStyle
+		return short
+	},
+	"services.*.volumes.[]": func(node *yaml.Node, _ tree.Path) *yaml.Node {
+		typ := nodeValue(node, "type")
+		target := nodeValue(node, "target")
+		if typ == nil || target == nil {
+			return node
+		}
+		source := nodeValue(node, "source")
+		switch typ.Value {
+		case VolumeTypeBind:
+			// short syntax creates missing host paths, and is only detected as a bind mount for paths
+			bind := nodeValue(node, "bind")
+			if bind == nil || !hasOnlyKeys(bind, map[string]string{"create_host_path": "true"}) || nodeValue(bind, "create_host_path") == nil {
+				return node
+			}
+			if source == nil || !isPathLike(source.Value) {
+				return node
+			}
+		case VolumeTypeVolume:
+			if volume := nodeValue(node, "volume"); volume != nil && len(volume.Content) > 0 {
+				return node
+			}
+			if source != nil && isPathLike(source.Value) {
+				return node
+			}
+		default:
+			return node
+		}
+		if !hasOnlyKeys(node, map[string]string{"type": "", "source": "", "target": "", "read_only": "", "bind": "", "volume": ""}) {
+			return node
+		}
+		volume := target.Value
+		if source != nil {
+			volume = source.Value + ":" + volume
+		}
+		if readOnly := nodeValue(node, "read_only"); readOnly != nil && readOnly.Value == "true" {
+			if source == nil {
+				return node
+			}
+			volume += ":ro"
+		}
+		return stringNode(volume)
+	},
+	"services.*.secrets.[]": fileReferenceShortSyntax("/run/secrets/"),
+	"services.*.configs.[]": fileReferenceShortSyntax("/"),
+}
+
+func fileReferenceShortSyntax(prefix string) nodeTransformer {
+	return func(node *yaml.Node, _ tree.Path) *yaml.Node {
+		source := nodeValue(node, "source")
+		if source == nil || !hasOnlyKeys(node, map[string]string{"source": "", "target": prefix + source.Value}) {
+			return node
+		}
+		return stringNode(source.Value)
+	}
+}
+
+func isPathLike(s string) bool {
+	return s == "." || s == "~" || strings.HasPrefix(s, "/") || strings.HasPrefix(s, "./") ||
+		strings.HasPrefix(s, "../") || strings.HasPrefix(s, "~/")
+}
+
+// transformNodes applies the transformers to yaml nodes matching their path, after nested nodes have been processed
+func transformNodes(node *yaml.Node, p tree.Path, transformers map[tree.Path]nodeTransformer) *yaml.Node {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for i, n := range node.Content {
+			node.Content[i] = transformNodes(n, p, transformers)
+		}
+		return node
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			node.Content[i+1] = transformNodes(node.Content[i+1], p.Next(node.Content[i].Value), transformers)
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			node.Content[i] = transformNodes(n, p.Next(tree.PathMatchList), transformers)
+		}
+	}
+	for pattern, transformer := range transformers {
+		if p.Matches(pattern) {
+			return transformer(node, p)
+		}
+	}
+	return node
+}
+
+// nodeValue returns the value set for key by a yaml mapping node, or nil
+func nodeValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func removeKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// removeDefault removes key from a yaml mapping node when set to its default value
+func removeDefault(node *yaml.Node, key string, defaultValue string) {
+	if v := nodeValue(node, key); v != nil && v.Kind == yaml.ScalarNode && v.Value == defaultValue {
+		removeKey(node, key)
+	}
+}
+
+// hasOnlyKeys checks a yaml mapping node only sets the allowed keys, and those with a non-empty expected value are
+// set to this value
+func hasOnlyKeys(node *yaml.Node, allowed map[string]string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		expected, ok := allowed[node.Content[i].Value]
+		if !ok {
+			return false
+		}
+		value := node.Content[i+1]
+		if value.Kind != yaml.ScalarNode && value.Kind != yaml.MappingNode {
+			return false
+		}
+		if expected != "" && value.Value != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// sortMappingKeys sorts keys of the yaml mappings matching one of the paths
+func sortMappingKeys(node *yaml.Node, p tree.Path, patterns []tree.Path) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			sortMappingKeys(n, p, patterns)
+		}
+	case yaml.MappingNode:
+		for _, pattern := range patterns {
+			if p.Matches(pattern) {
+				sort.Sort(mappingNode(node.Content))
+				break
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			sortMappingKeys(node.Content[i+1], p.Next(node.Content[i].Value), patterns)
+		}
+	}
+}
+
+// mappingNode sorts the content of a yaml mapping node, made of key/value pairs, by key
+type mappingNode []*yaml.Node
+
+func (m mappingNode) Len() int {
+	return len(m) / 2
+}
+
+func (m mappingNode) Less(i, j int) bool {
+	return m[2*i].Value < m[2*j].Value
+}
+
+func (m mappingNode) Swap(i, j int) {
+	m[2*i], m[2*j] = m[2*j], m[2*i]
+	m[2*i+1], m[2*j+1] = m[2*j+1], m[2*i+1]
+}
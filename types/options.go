@@ -16,7 +16,10 @@
 
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 // Options is a mapping type for options we pass as-is to container runtime
 type Options map[string]string
@@ -26,9 +29,13 @@ func (d *Options) DecodeMapstructure(value interface{}) error {
 	case map[string]interface{}:
 		m := make(map[string]string)
 		for key, e := range v {
-			if e == nil {
+			switch e := e.(type) {
+			case nil:
 				m[key] = ""
-			} else {
+			case float64:
+				// prevent large or small numbers from being rendered with an exponent
+				m[key] = strconv.FormatFloat(e, 'f', -1, 64)
+			default:
 				m[key] = fmt.Sprint(e)
 			}
 		}
@@ -0,0 +1,112 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// Ports is a list of ports published by a service
+type Ports []ServicePortConfig
+
+// Contains returns true if one of the ports publishes host port `published` using protocol (defaults to tcp)
+func (p Ports) Contains(published int, protocol string) bool {
+	for _, port := range p {
+		if port.protocol() != normalizeProtocol(protocol) {
+			continue
+		}
+		start, end, ok := port.publishedRange()
+		if ok && published >= start && published <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns the ports with published port ranges flattened into one ServicePortConfig per host port
+func (p Ports) Expand() []ServicePortConfig {
+	var expanded []ServicePortConfig
+	for _, port := range p {
+		expanded = append(expanded, port.Expand()...)
+	}
+	return expanded
+}
+
+// Expand returns a ServicePortConfig for each host port within the published range
+func (s ServicePortConfig) Expand() []ServicePortConfig {
+	start, end, ok := s.publishedRange()
+	if !ok || start == end {
+		return []ServicePortConfig{s}
+	}
+	var expanded []ServicePortConfig
+	for i := start; i <= end; i++ {
+		port := s
+		port.Published = strconv.Itoa(i)
+		expanded = append(expanded, port)
+	}
+	return expanded
+}
+
+// Overlaps returns true if both ports publish a common host port, with the same protocol, on the same host IP
+func (s ServicePortConfig) Overlaps(other ServicePortConfig) bool {
+	if s.protocol() != other.protocol() {
+		return false
+	}
+	if !isUnspecifiedIP(s.HostIP) && !isUnspecifiedIP(other.HostIP) && s.HostIP != other.HostIP {
+		return false
+	}
+	start, end, ok := s.publishedRange()
+	if !ok {
+		return false
+	}
+	otherStart, otherEnd, ok := other.publishedRange()
+	if !ok {
+		return false
+	}
+	return start <= otherEnd && otherStart <= end
+}
+
+// publishedRange returns the range of host ports published by this port, which is false if no (valid) published
+// port is set, as the engine will then allocate a random host port
+func (s ServicePortConfig) publishedRange() (int, int, bool) {
+	if s.Published == "" {
+		return 0, 0, false
+	}
+	start, end, err := nat.ParsePortRangeToInt(s.Published)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func (s ServicePortConfig) protocol() string {
+	return normalizeProtocol(s.Protocol)
+}
+
+func normalizeProtocol(protocol string) string {
+	if protocol == "" {
+		return "tcp"
+	}
+	return strings.ToLower(protocol)
+}
+
+func isUnspecifiedIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
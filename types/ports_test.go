@@ -0,0 +1,115 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPortsContains(t *testing.T) {
+	var ports Ports
+	for _, spec := range []string{"8000-8002:80-82", "127.0.0.1:9000-9001:90/udp", "3000"} {
+		parsed, err := ParsePortConfig(spec)
+		assert.NilError(t, err)
+		ports = append(ports, parsed...)
+	}
+	ports = append(ports, ServicePortConfig{Target: 443, Published: "4430-4439"})
+
+	assert.Check(t, ports.Contains(8001, "tcp"))
+	assert.Check(t, ports.Contains(8002, ""))
+	assert.Check(t, !ports.Contains(8001, "udp"))
+	assert.Check(t, ports.Contains(9001, "UDP"))
+	assert.Check(t, !ports.Contains(9001, "tcp"))
+	assert.Check(t, ports.Contains(4435, "tcp"))
+	assert.Check(t, !ports.Contains(3000, "tcp"), "target port without published port must not be considered")
+	assert.Check(t, !ports.Contains(80, "tcp"))
+}
+
+func TestPortsExpand(t *testing.T) {
+	ports := Ports{
+		{Target: 80, Published: "8000-8002", Protocol: "tcp"},
+		{Target: 90, Published: "9000", Protocol: "udp"},
+		{Target: 3000},
+	}
+	assert.DeepEqual(t, ports.Expand(), []ServicePortConfig{
+		{Target: 80, Published: "8000", Protocol: "tcp"},
+		{Target: 80, Published: "8001", Protocol: "tcp"},
+		{Target: 80, Published: "8002", Protocol: "tcp"},
+		{Target: 90, Published: "9000", Protocol: "udp"},
+		{Target: 3000},
+	})
+}
+
+func TestPortOverlaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        ServicePortConfig
+		b        ServicePortConfig
+		overlaps bool
+	}{
+		{
+			name:     "same port",
+			a:        ServicePortConfig{Target: 80, Published: "8080"},
+			b:        ServicePortConfig{Target: 81, Published: "8080", Protocol: "tcp"},
+			overlaps: true,
+		},
+		{
+			name:     "distinct protocols",
+			a:        ServicePortConfig{Target: 80, Published: "8080"},
+			b:        ServicePortConfig{Target: 80, Published: "8080", Protocol: "udp"},
+			overlaps: false,
+		},
+		{
+			name:     "ranges",
+			a:        ServicePortConfig{Target: 80, Published: "8000-8010"},
+			b:        ServicePortConfig{Target: 80, Published: "8010-8020"},
+			overlaps: true,
+		},
+		{
+			name:     "disjoint ranges",
+			a:        ServicePortConfig{Target: 80, Published: "8000-8009"},
+			b:        ServicePortConfig{Target: 80, Published: "8010-8020"},
+			overlaps: false,
+		},
+		{
+			name:     "distinct host IPs",
+			a:        ServicePortConfig{HostIP: "127.0.0.1", Target: 80, Published: "8080"},
+			b:        ServicePortConfig{HostIP: "192.168.1.1", Target: 80, Published: "8080"},
+			overlaps: false,
+		},
+		{
+			name:     "any host IP",
+			a:        ServicePortConfig{HostIP: "127.0.0.1", Target: 80, Published: "8080"},
+			b:        ServicePortConfig{HostIP: "0.0.0.0", Target: 80, Published: "8080"},
+			overlaps: true,
+		},
+		{
+			name:     "not published",
+			a:        ServicePortConfig{Target: 80},
+			b:        ServicePortConfig{Target: 80},
+			overlaps: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.a.Overlaps(tt.b), tt.overlaps)
+			assert.Equal(t, tt.b.Overlaps(tt.a), tt.overlaps)
+		})
+	}
+}
@@ -19,17 +19,24 @@ package types
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/compose-spec/compose-go/v2/dotenv"
+	"github.com/compose-spec/compose-go/v2/errdefs"
+	"github.com/compose-spec/compose-go/v2/tree"
 	"github.com/compose-spec/compose-go/v2/utils"
 	"github.com/distribution/reference"
 	"github.com/mitchellh/copystructure"
 	godigest "github.com/opencontainers/go-digest"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 )
@@ -55,64 +62,149 @@ type Project struct {
 	Profiles         []string `yaml:"-" json:"-"`
 }
 
-// ServiceNames return names for all services in this Compose config
+// ServiceNames return sorted names for all services in this Compose config
 func (p *Project) ServiceNames() []string {
-	var names []string
-	for k := range p.Services {
-		names = append(names, k)
+	if len(p.Services) == 0 {
+		return nil
 	}
-	sort.Strings(names)
-	return names
+	return utils.MapKeys(p.Services)
 }
 
-// DisabledServiceNames return names for all disabled services in this Compose config
+// DisabledServiceNames return sorted names for all disabled services in this Compose config
 func (p *Project) DisabledServiceNames() []string {
-	var names []string
-	for k := range p.DisabledServices {
-		names = append(names, k)
+	if len(p.DisabledServices) == 0 {
+		return nil
 	}
-	sort.Strings(names)
-	return names
+	return utils.MapKeys(p.DisabledServices)
 }
 
-// VolumeNames return names for all volumes in this Compose config
+// VolumeNames return sorted names for all volumes in this Compose config
 func (p *Project) VolumeNames() []string {
-	var names []string
-	for k := range p.Volumes {
-		names = append(names, k)
+	if len(p.Volumes) == 0 {
+		return nil
 	}
-	sort.Strings(names)
-	return names
+	return utils.MapKeys(p.Volumes)
 }
 
-// NetworkNames return names for all volumes in this Compose config
+// NetworkNames return sorted names for all networks in this Compose config
 func (p *Project) NetworkNames() []string {
-	var names []string
-	for k := range p.Networks {
-		names = append(names, k)
+	if len(p.Networks) == 0 {
+		return nil
 	}
-	sort.Strings(names)
-	return names
+	return utils.MapKeys(p.Networks)
 }
 
-// SecretNames return names for all secrets in this Compose config
+// SecretNames return sorted names for all secrets in this Compose config
 func (p *Project) SecretNames() []string {
-	var names []string
-	for k := range p.Secrets {
-		names = append(names, k)
+	if len(p.Secrets) == 0 {
+		return nil
 	}
-	sort.Strings(names)
-	return names
+	return utils.MapKeys(p.Secrets)
 }
 
-// ConfigNames return names for all configs in this Compose config
+// ConfigNames return sorted names for all configs in this Compose config
 func (p *Project) ConfigNames() []string {
-	var names []string
-	for k := range p.Configs {
-		names = append(names, k)
+	if len(p.Configs) == 0 {
+		return nil
+	}
+	return utils.MapKeys(p.Configs)
+}
+
+// AllProfiles return sorted names for all profiles declared by services in this Compose config, including
+// disabled ones
+func (p *Project) AllProfiles() []string {
+	profiles := map[string]struct{}{}
+	for _, s := range p.AllServices() {
+		for _, profile := range s.Profiles {
+			profiles[profile] = struct{}{}
+		}
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+	return utils.MapKeys(profiles)
+}
+
+// VolumeMountSite describes a service mounting a volume
+type VolumeMountSite struct {
+	Service string
+	Target  string
+}
+
+// VolumeUsage returns, for each named volume, the services mounting it and the mount targets.
+// Bind mounts, tmpfs and anonymous volumes are ignored.
+func (p *Project) VolumeUsage() map[string][]VolumeMountSite {
+	usage := map[string][]VolumeMountSite{}
+	for _, name := range p.ServiceNames() {
+		for _, volume := range p.Services[name].Volumes {
+			if volume.Type != VolumeTypeVolume || volume.Source == "" {
+				continue
+			}
+			usage[volume.Source] = append(usage[volume.Source], VolumeMountSite{
+				Service: name,
+				Target:  volume.Target,
+			})
+		}
+	}
+	return usage
+}
+
+// ServicesAffectedByPath returns the names of the services which depend on the file or directory at path,
+// as it belongs to the service build context, a bind mount or is an env_file or label_file.
+// Relative paths are resolved from the project working directory.
+func (p *Project) ServicesAffectedByPath(path string) []string {
+	path = p.absPath(path)
+	var affected []string
+	for _, name := range p.ServiceNames() {
+		s := p.Services[name]
+		if isServiceAffectedByPath(p, s, path) {
+			affected = append(affected, name)
+		}
+	}
+	return affected
+}
+
+func isServiceAffectedByPath(p *Project, s ServiceConfig, path string) bool {
+	if s.Build != nil && !isRemoteBuildContext(s.Build.Context) && isWithin(path, p.absPath(s.Build.Context)) {
+		return true
 	}
-	sort.Strings(names)
-	return names
+	for _, v := range s.Volumes {
+		if v.Type == VolumeTypeBind && isWithin(path, p.absPath(v.Source)) {
+			return true
+		}
+	}
+	for _, f := range s.EnvFiles {
+		if p.absPath(f.Path) == path {
+			return true
+		}
+	}
+	for _, f := range s.LabelFiles {
+		if p.absPath(f) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath resolves path relative to the project working directory
+func (p *Project) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(p.WorkingDir, path)
+}
+
+// isWithin returns true if path is dir or one of its descendants
+func isWithin(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func isRemoteBuildContext(context string) bool {
+	return strings.Contains(context, "://") || strings.HasPrefix(context, "git@")
 }
 
 func (p *Project) ServicesWithBuild() []string {
@@ -140,16 +232,16 @@ func (p *Project) ServicesWithCapabilities() ([]string, []string, []string) {
 	capabilities := []string{}
 	gpu := []string{}
 	tpu := []string{}
-	for _, service := range p.Services {
-		deploy := service.Deploy
-		if deploy == nil {
-			continue
+	for _, service := range p.OrderedServices() {
+		var devices []DeviceRequest
+		if deploy := service.Deploy; deploy != nil && deploy.Resources.Reservations != nil {
+			devices = deploy.Resources.Reservations.Devices
 		}
-		reservation := deploy.Resources.Reservations
-		if reservation == nil {
-			continue
+		if len(service.Gpus) > 0 {
+			// gpus implicitly request the gpu capability
+			capabilities = append(capabilities, service.Name)
+			gpu = append(gpu, service.Name)
 		}
-		devices := reservation.Devices
 		for _, d := range devices {
 			if len(d.Capabilities) > 0 {
 				capabilities = append(capabilities, service.Name)
@@ -217,11 +309,61 @@ func (p *Project) GetService(name string) (ServiceConfig, error) {
 		if ok {
 			return ServiceConfig{}, fmt.Errorf("service %s is disabled", name)
 		}
-		return ServiceConfig{}, fmt.Errorf("no such service: %s", name)
+		return ServiceConfig{}, p.serviceNotFoundError(name)
 	}
 	return service, nil
 }
 
+// GetServiceByContainerName retrieves the service running a container, identified either by the service's
+// container_name or by the default container name `<project>-<service>-<index>` (or its legacy `_` separated form)
+func (p *Project) GetServiceByContainerName(name string) (ServiceConfig, error) {
+	for _, serviceName := range p.ServiceNames() {
+		service := p.Services[serviceName]
+		if service.ContainerName != "" {
+			if service.ContainerName == name {
+				return service, nil
+			}
+			continue
+		}
+		if isDefaultContainerName(name, p.Name, serviceName) {
+			return service, nil
+		}
+	}
+	return ServiceConfig{}, fmt.Errorf("no service with container name %s: %w", name, errdefs.ErrNotFound)
+}
+
+// isDefaultContainerName checks name is a container name compose would assign to a replica of service
+func isDefaultContainerName(name string, project string, service string) bool {
+	for _, separator := range []string{"-", "_"} {
+		prefix := project + separator + service + separator
+		index, ok := strings.CutPrefix(name, prefix)
+		if !ok || index == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(index); err == nil && n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Project) serviceNotFoundError(name string) error {
+	names := p.ServiceNames()
+	if len(names) == 0 {
+		return fmt.Errorf("no such service: %s: %w", name, errdefs.ErrNotFound)
+	}
+	return fmt.Errorf("no such service: %s, available services are %s: %w", name, strings.Join(names, ", "), errdefs.ErrNotFound)
+}
+
+// OrderedServices returns enabled services sorted by name, for callers which need a deterministic iteration order
+func (p *Project) OrderedServices() []ServiceConfig {
+	services := make([]ServiceConfig, 0, len(p.Services))
+	for _, name := range p.ServiceNames() {
+		services = append(services, p.Services[name])
+	}
+	return services
+}
+
 func (p *Project) AllServices() Services {
 	all := Services{}
 	for name, service := range p.Services {
@@ -297,6 +439,37 @@ func (p *Project) withServices(names []string, fn ServiceFunc, seen map[string]b
 	return nil
 }
 
+// ReachableFrom returns the sorted names of the given services and all the services they transitively depend on.
+// Implicit dependencies (links, volumes_from, network_mode, ...) are considered as long as the project has been
+// normalized, as those are then declared in depends_on. Dependency cycles are not an error, all services involved
+// in the cycle are part of the result.
+func (p *Project) ReachableFrom(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	queue := append([]string{}, names...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		service, err := p.GetService(name)
+		if err != nil {
+			return nil, err
+		}
+		seen[name] = true
+		for dep, dependency := range service.DependsOn {
+			if _, ok := p.Services[dep]; !ok {
+				if !dependency.Required {
+					continue
+				}
+				return nil, fmt.Errorf("service %q depends on unknown service %q", name, dep)
+			}
+			queue = append(queue, dep)
+		}
+	}
+	return utils.MapKeys(seen), nil
+}
+
 func (p *Project) GetDependentsForService(s ServiceConfig) []string {
 	return utils.MapKeys(p.dependentsForService(s))
 }
@@ -325,6 +498,68 @@ func (p *Project) RelativePath(path string) string {
 	return filepath.Join(p.WorkingDir, path)
 }
 
+// RelativePaths rewrites absolute paths located inside baseDir as paths relative to baseDir, so the model can be
+// written as a portable compose file. This applies to the attributes resolved by the loader: build context and
+// additional_contexts, bind mount sources, env_file and label_file, extends file, develop watch paths, volume device
+// driver option, and configs and secrets file. Paths outside baseDir are left absolute.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p *Project) RelativePaths(baseDir string) *Project {
+	newProject := p.DeepCopy()
+	rel := func(path string) string {
+		if !filepath.IsAbs(path) || !isWithin(path, baseDir) {
+			return path
+		}
+		r, _ := filepath.Rel(baseDir, path)
+		if r == "." {
+			return r
+		}
+		return "./" + filepath.ToSlash(r)
+	}
+	for name, s := range newProject.Services {
+		if s.Build != nil {
+			s.Build.Context = rel(s.Build.Context)
+			for k, v := range s.Build.AdditionalContexts {
+				s.Build.AdditionalContexts[k] = rel(v)
+			}
+		}
+		for i, v := range s.Volumes {
+			if v.Type == VolumeTypeBind {
+				s.Volumes[i].Source = rel(v.Source)
+			}
+		}
+		for i, f := range s.EnvFiles {
+			s.EnvFiles[i].Path = rel(f.Path)
+		}
+		for i, f := range s.LabelFiles {
+			s.LabelFiles[i] = rel(f)
+		}
+		if s.Extends != nil {
+			s.Extends.File = rel(s.Extends.File)
+		}
+		if s.Develop != nil {
+			for i, w := range s.Develop.Watch {
+				s.Develop.Watch[i].Path = rel(w.Path)
+			}
+		}
+		newProject.Services[name] = s
+	}
+	for name, v := range newProject.Volumes {
+		if device, ok := v.DriverOpts["device"]; ok {
+			v.DriverOpts["device"] = rel(device)
+		}
+		newProject.Volumes[name] = v
+	}
+	for name, c := range newProject.Configs {
+		c.File = rel(c.File)
+		newProject.Configs[name] = c
+	}
+	for name, s := range newProject.Secrets {
+		s.File = rel(s.File)
+		newProject.Secrets[name] = s
+	}
+	return newProject
+}
+
 // HasProfile return true if service has no profile declared or has at least one profile matching
 func (s ServiceConfig) HasProfile(profiles []string) bool {
 	if len(s.Profiles) == 0 {
@@ -343,31 +578,43 @@ func (s ServiceConfig) HasProfile(profiles []string) bool {
 // WithProfiles disables services which don't match selected profiles
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p *Project) WithProfiles(profiles []string) (*Project, error) {
-	newProject := p.deepCopy()
+	newProject, _, err := p.ApplyProfiles(profiles)
+	return newProject, err
+}
+
+// ApplyProfiles disables services which don't match selected profiles, like WithProfiles, and also returns
+// the sorted names of the services excluded this way. Services without profiles are always enabled, so only
+// services gated behind profiles which are not active are excluded.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p *Project) ApplyProfiles(profiles []string) (*Project, []string, error) {
+	newProject := p.DeepCopy()
 	for _, p := range profiles {
 		if p == "*" {
-			return newProject, nil
+			return newProject, nil, nil
 		}
 	}
 	enabled := Services{}
 	disabled := Services{}
+	var excluded []string
 	for name, service := range newProject.AllServices() {
 		if service.HasProfile(profiles) {
 			enabled[name] = service
 		} else {
 			disabled[name] = service
+			excluded = append(excluded, name)
 		}
 	}
+	sort.Strings(excluded)
 	newProject.Services = enabled
 	newProject.DisabledServices = disabled
 	newProject.Profiles = profiles
-	return newProject, nil
+	return newProject, excluded, nil
 }
 
 // WithServicesEnabled ensures services are enabled and activate profiles accordingly
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p *Project) WithServicesEnabled(names ...string) (*Project, error) {
-	newProject := p.deepCopy()
+	newProject := p.DeepCopy()
 	if len(names) == 0 {
 		return newProject, nil
 	}
@@ -392,33 +639,8 @@ func (p *Project) WithServicesEnabled(names ...string) (*Project, error) {
 // WithoutUnnecessaryResources drops networks/volumes/secrets/configs that are not referenced by active services
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p *Project) WithoutUnnecessaryResources() *Project {
-	newProject := p.deepCopy()
-	requiredNetworks := map[string]struct{}{}
-	requiredVolumes := map[string]struct{}{}
-	requiredSecrets := map[string]struct{}{}
-	requiredConfigs := map[string]struct{}{}
-	for _, s := range newProject.Services {
-		for k := range s.Networks {
-			requiredNetworks[k] = struct{}{}
-		}
-		for _, v := range s.Volumes {
-			if v.Type != VolumeTypeVolume || v.Source == "" {
-				continue
-			}
-			requiredVolumes[v.Source] = struct{}{}
-		}
-		for _, v := range s.Secrets {
-			requiredSecrets[v.Source] = struct{}{}
-		}
-		if s.Build != nil {
-			for _, v := range s.Build.Secrets {
-				requiredSecrets[v.Source] = struct{}{}
-			}
-		}
-		for _, v := range s.Configs {
-			requiredConfigs[v.Source] = struct{}{}
-		}
-	}
+	newProject := p.DeepCopy()
+	requiredNetworks, requiredVolumes, requiredSecrets, requiredConfigs := newProject.resourcesInUse()
 
 	networks := Networks{}
 	for k := range requiredNetworks {
@@ -454,6 +676,99 @@ func (p *Project) WithoutUnnecessaryResources() *Project {
 	return newProject
 }
 
+// resourcesInUse returns the names of networks, volumes, secrets and configs referenced by active services
+func (p *Project) resourcesInUse() (networks, volumes, secrets, configs map[string]struct{}) {
+	networks = map[string]struct{}{}
+	volumes = map[string]struct{}{}
+	secrets = map[string]struct{}{}
+	configs = map[string]struct{}{}
+	for _, s := range p.Services {
+		for k := range s.Networks {
+			networks[k] = struct{}{}
+		}
+		for _, v := range s.Volumes {
+			if v.Type != VolumeTypeVolume || v.Source == "" {
+				continue
+			}
+			volumes[v.Source] = struct{}{}
+		}
+		for _, v := range s.Secrets {
+			secrets[v.Source] = struct{}{}
+		}
+		if s.Build != nil {
+			for _, v := range s.Build.Secrets {
+				secrets[v.Source] = struct{}{}
+			}
+		}
+		for _, v := range s.Configs {
+			configs[v.Source] = struct{}{}
+		}
+	}
+	return networks, volumes, secrets, configs
+}
+
+// ProfilesExtension is the extension used to gate top-level networks, volumes, secrets and configs behind
+// profiles, as the compose specification only defines profiles for services. See Project.WithResourceProfiles
+const ProfilesExtension = "x-profiles"
+
+// WithResourceProfiles drops networks, volumes, secrets and configs declaring profiles with ProfilesExtension
+// when none of them is selected. Resources referenced by an active service are kept regardless of their profiles.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p *Project) WithResourceProfiles(profiles []string) *Project {
+	newProject := p.DeepCopy()
+	if slices.Contains(profiles, "*") {
+		return newProject
+	}
+	networks, volumes, secrets, configs := newProject.resourcesInUse()
+	for name, n := range newProject.Networks {
+		if _, ok := networks[name]; !ok && !hasResourceProfile(n.Extensions, profiles) {
+			delete(newProject.Networks, name)
+		}
+	}
+	for name, v := range newProject.Volumes {
+		if _, ok := volumes[name]; !ok && !hasResourceProfile(v.Extensions, profiles) {
+			delete(newProject.Volumes, name)
+		}
+	}
+	for name, s := range newProject.Secrets {
+		if _, ok := secrets[name]; !ok && !hasResourceProfile(s.Extensions, profiles) {
+			delete(newProject.Secrets, name)
+		}
+	}
+	for name, c := range newProject.Configs {
+		if _, ok := configs[name]; !ok && !hasResourceProfile(c.Extensions, profiles) {
+			delete(newProject.Configs, name)
+		}
+	}
+	return newProject
+}
+
+// hasResourceProfile return true if resource has no profile declared or has at least one profile matching
+func hasResourceProfile(extensions Extensions, profiles []string) bool {
+	var declared []string
+	switch v := extensions[ProfilesExtension].(type) {
+	case nil:
+		return true
+	case string:
+		declared = []string{v}
+	case []string:
+		declared = v
+	case []any:
+		for _, e := range v {
+			declared = append(declared, fmt.Sprint(e))
+		}
+	}
+	if len(declared) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if slices.Contains(declared, p) {
+			return true
+		}
+	}
+	return false
+}
+
 type DependencyOption func(options *withServicesOptions)
 
 func IncludeDependencies(options *withServicesOptions) {
@@ -471,7 +786,7 @@ func IgnoreDependencies(options *withServicesOptions) {
 // WithSelectedServices restricts the project model to selected services and dependencies
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p *Project) WithSelectedServices(names []string, options ...DependencyOption) (*Project, error) {
-	newProject := p.deepCopy()
+	newProject := p.DeepCopy()
 	if len(names) == 0 {
 		// All services
 		return newProject, nil
@@ -507,10 +822,60 @@ func (p *Project) WithSelectedServices(names []string, options ...DependencyOpti
 	return newProject, nil
 }
 
+// ForServices restricts the project model to selected services and their dependencies, like WithSelectedServices,
+// then removes networks, volumes, secrets and configs which are not used anymore. Pass IgnoreDependencies to only
+// keep the selected services, typically for a one-off command.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p *Project) ForServices(names []string, options ...DependencyOption) (*Project, error) {
+	newProject, err := p.WithSelectedServices(names, options...)
+	if err != nil {
+		return nil, err
+	}
+	return newProject.WithoutUnnecessaryResources(), nil
+}
+
+// WithServicesTransform applies a transformation to every enabled service. All services are processed, in name
+// order, and errors returned by fn are joined, so that a single call reports all services which failed.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p *Project) WithServicesTransform(fn func(ServiceConfig) (ServiceConfig, error)) (*Project, error) {
+	newProject := p.DeepCopy()
+	var errs []error
+	for _, name := range newProject.ServiceNames() {
+		s, err := fn(newProject.Services[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("services.%s: %w", name, err))
+			continue
+		}
+		newProject.Services[name] = s
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return newProject, nil
+}
+
+// WithContainerNames sets container_name for services which don't declare one, using ServiceConfig.ContainerNameFor
+// with pattern. As container_name must be unique, services running more than one container are left unchanged, and
+// their container names are to be derived with ServiceConfig.ContainerNameFor for each index.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p *Project) WithContainerNames(pattern string) (*Project, error) {
+	return p.WithServicesTransform(func(s ServiceConfig) (ServiceConfig, error) {
+		if s.ContainerName != "" || s.GetScale() > 1 {
+			return s, nil
+		}
+		name, err := s.ContainerNameFor(pattern, p.Name, 1)
+		if err != nil {
+			return s, err
+		}
+		s.ContainerName = name
+		return s, nil
+	})
+}
+
 // WithServicesDisabled removes from the project model the given services and their references in all dependencies
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p *Project) WithServicesDisabled(names ...string) *Project {
-	newProject := p.deepCopy()
+	newProject := p.DeepCopy()
 	if len(names) == 0 {
 		return newProject
 	}
@@ -536,7 +901,7 @@ func (p *Project) WithServicesDisabled(names ...string) *Project {
 // WithImagesResolved updates services images to include digest computed by a resolver function
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p *Project) WithImagesResolved(resolver func(named reference.Named) (godigest.Digest, error)) (*Project, error) {
-	newProject := p.deepCopy()
+	newProject := p.DeepCopy()
 	eg := errgroup.Group{}
 	for i, s := range newProject.Services {
 		idx := i
@@ -573,24 +938,53 @@ func (p *Project) WithImagesResolved(resolver func(named reference.Named) (godig
 
 // MarshalYAML marshal Project into a yaml tree
 func (p *Project) MarshalYAML() ([]byte, error) {
+	return p.MarshalYAMLWithOptions()
+}
+
+// MarshalYAMLWithOptions marshal Project into a yaml tree, applying MarshalOptions
+func (p *Project) MarshalYAMLWithOptions(options ...MarshalOption) ([]byte, error) {
+	opts := marshalOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	var v interface{} = p
+	if len(opts.sorted) > 0 || opts.omitDefaults || opts.shortSyntax {
+		var node yaml.Node
+		if err := node.Encode(p); err != nil {
+			return nil, err
+		}
+		if opts.omitDefaults {
+			transformNodes(&node, tree.NewPath(), defaultValuesRemovers(p.Name))
+		}
+		if opts.shortSyntax {
+			transformNodes(&node, tree.NewPath(), shortSyntaxConverters)
+		}
+		sortMappingKeys(&node, tree.NewPath(), opts.sorted)
+		v = &node
+	}
+
 	buf := bytes.NewBuffer([]byte{})
 	encoder := yaml.NewEncoder(buf)
 	encoder.SetIndent(2)
 	// encoder.CompactSeqIndent() FIXME https://github.com/go-yaml/yaml/pull/753
-	err := encoder.Encode(p)
+	err := encoder.Encode(v)
 	if err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// MarshalJSON makes Config implement json.Marshaler
+// MarshalJSON makes Project implement json.Marshaler
 func (p *Project) MarshalJSON() ([]byte, error) {
 	m := map[string]interface{}{
-		"name":     p.Name,
 		"services": p.Services,
 	}
 
+	if p.Name != "" {
+		m["name"] = p.Name
+	}
+
 	if len(p.Networks) > 0 {
 		m["networks"] = p.Networks
 	}
@@ -609,12 +1003,55 @@ func (p *Project) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+// Encode writes the project model to w, formatted as "yaml" or "json". MarshalOptions only apply to yaml
+func (p *Project) Encode(w io.Writer, format string, options ...MarshalOption) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch format {
+	case "yaml":
+		b, err = p.MarshalYAMLWithOptions(options...)
+	case "json":
+		b, err = json.MarshalIndent(p, "", "  ")
+		b = append(b, '\n')
+	default:
+		return fmt.Errorf("unsupported format %q, expected either \"yaml\" or \"json\"", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
 // WithServicesEnvironmentResolved parses env_files set for services to resolve the actual environment map for services
 // It returns a new Project instance with the changes and keep the original Project unchanged
 func (p Project) WithServicesEnvironmentResolved(discardEnvFiles bool) (*Project, error) {
-	newProject := p.deepCopy()
-	for i, service := range newProject.Services {
-		service.Environment = service.Environment.Resolve(newProject.Environment.Resolve)
+	return p.withServicesEnvironmentResolved(p.Environment.Resolve, discardEnvFiles, false)
+}
+
+// WithServicesEnvironmentMaterialized returns a project where every service environment is a complete set of
+// `KEY=value` entries. Precedence, from lowest to highest, is:
+//   - env_file entries, in declaration order, later files overriding earlier ones
+//   - environment entries with a value, including an empty one (`KEY=`)
+//   - environment entries without a value (`KEY`), resolved by lookupFn
+//
+// An entry without a value lookupFn can't resolve keeps the value set by an env_file, or is removed.
+// Variables used by env_file values are resolved from previously parsed env_files, then lookupFn.
+// It returns a new Project instance with the changes and keep the original Project unchanged
+func (p Project) WithServicesEnvironmentMaterialized(lookupFn func(string) (string, bool), discardEnvFiles bool) (*Project, error) {
+	if lookupFn == nil {
+		lookupFn = func(string) (string, bool) { return "", false }
+	}
+	return p.withServicesEnvironmentResolved(lookupFn, discardEnvFiles, true)
+}
+
+func (p Project) withServicesEnvironmentResolved(lookupFn func(string) (string, bool), discardEnvFiles bool, materialize bool) (*Project, error) {
+	newProject := p.DeepCopy()
+	for _, name := range newProject.ServiceNames() {
+		service := newProject.Services[name]
+		service.Environment = service.Environment.Resolve(lookupFn)
 
 		environment := MappingWithEquals{}
 		// resolve variables based on other files we already parsed, + project's environment
@@ -623,7 +1060,7 @@ func (p Project) WithServicesEnvironmentResolved(discardEnvFiles bool) (*Project
 			if ok && v != nil {
 				return *v, ok
 			}
-			return newProject.Environment.Resolve(s)
+			return lookupFn(s)
 		}
 
 		for _, envFile := range service.EnvFiles {
@@ -645,17 +1082,24 @@ func (p Project) WithServicesEnvironmentResolved(discardEnvFiles bool) (*Project
 			environment.OverrideBy(Mapping(fileVars).ToMappingWithEquals())
 		}
 
-		service.Environment = environment.OverrideBy(service.Environment)
+		if materialize {
+			// entries left without a value don't override env_file
+			service.Environment = environment.OverrideBy(service.Environment.RemoveEmpty())
+		} else {
+			service.Environment = environment.OverrideBy(service.Environment)
+		}
 
 		if discardEnvFiles {
 			service.EnvFiles = nil
 		}
-		newProject.Services[i] = service
+		newProject.Services[name] = service
 	}
 	return newProject, nil
 }
 
-func (p *Project) deepCopy() *Project {
+// DeepCopy returns a copy of the project which doesn't share any map, slice or pointer with the original one,
+// so that either can be modified without affecting the other
+func (p *Project) DeepCopy() *Project {
 	instance, err := copystructure.Copy(p)
 	if err != nil {
 		panic(err)
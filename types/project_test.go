@@ -17,9 +17,16 @@
 package types
 
 import (
+	"bytes"
 	_ "crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/compose-spec/compose-go/v2/errdefs"
 	"github.com/compose-spec/compose-go/v2/utils"
 	"github.com/distribution/reference"
 	"github.com/opencontainers/go-digest"
@@ -42,6 +49,45 @@ func Test_ApplyProfiles(t *testing.T) {
 
 }
 
+func Test_ApplyProfilesExcluded(t *testing.T) {
+	p := makeProject()
+	enabled, excluded, err := p.ApplyProfiles([]string{"foo"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, enabled.ServiceNames(), []string{"service_1", "service_2", "service_6"})
+	assert.DeepEqual(t, excluded, []string{"service_3", "service_4", "service_5"})
+	assert.DeepEqual(t, enabled.DisabledServices["service_3"].Profiles, []string{"bar"})
+
+	// services without profiles are never excluded
+	_, excluded, err = p.ApplyProfiles(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, excluded, []string{"service_2", "service_3", "service_4", "service_5"})
+
+	_, excluded, err = p.ApplyProfiles([]string{"*"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(excluded), 0)
+}
+
+func Test_ApplyProfilesAnyMatch(t *testing.T) {
+	p := &Project{
+		Services: Services{
+			"always":   ServiceConfig{Name: "always"},
+			"empty":    ServiceConfig{Name: "empty", Profiles: []string{}},
+			"multiple": ServiceConfig{Name: "multiple", Profiles: []string{"debug", "test"}},
+			"other":    ServiceConfig{Name: "other", Profiles: []string{"prod"}},
+		},
+	}
+
+	enabled, excluded, err := p.ApplyProfiles([]string{"test"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, enabled.ServiceNames(), []string{"always", "empty", "multiple"})
+	assert.DeepEqual(t, excluded, []string{"other"})
+
+	enabled, excluded, err = p.ApplyProfiles(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, enabled.ServiceNames(), []string{"always", "empty"})
+	assert.DeepEqual(t, excluded, []string{"multiple", "other"})
+}
+
 func Test_WithoutUnnecessaryResources(t *testing.T) {
 	p := makeProject()
 	p.Networks["unused"] = NetworkConfig{}
@@ -127,6 +173,121 @@ func Test_ForServicesCycle(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func Test_ForServicesResources(t *testing.T) {
+	p := makeProject()
+	p.Networks["front"] = NetworkConfig{}
+	p.Volumes["data"] = VolumeConfig{}
+	p.Secrets["token"] = SecretConfig{}
+	service := p.Services["service_1"]
+	service.Networks = map[string]*ServiceNetworkConfig{"front": nil}
+	p.Services["service_1"] = service
+	service = p.Services["service_3"]
+	service.Volumes = []ServiceVolumeConfig{{Type: VolumeTypeVolume, Source: "data", Target: "/data"}}
+	service.Secrets = []ServiceSecretConfig{{Source: "token"}}
+	p.Services["service_3"] = service
+
+	selected, err := p.ForServices([]string{"service_2"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, selected.ServiceNames(), []string{"service_1", "service_2"})
+	assert.DeepEqual(t, selected.NetworkNames(), []string{"front"})
+	assert.Check(t, selected.VolumeNames() == nil)
+	assert.Check(t, selected.SecretNames() == nil)
+
+	selected, err = p.ForServices([]string{"service_3"}, IgnoreDependencies)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, selected.ServiceNames(), []string{"service_3"})
+	assert.Check(t, selected.NetworkNames() == nil)
+	assert.DeepEqual(t, selected.VolumeNames(), []string{"data"})
+	assert.DeepEqual(t, selected.SecretNames(), []string{"token"})
+
+	_, err = p.ForServices([]string{"unknown"})
+	assert.Error(t, err, "no such service: unknown")
+
+	// original project is unchanged
+	assert.Equal(t, len(p.Services), 6)
+	assert.Equal(t, len(p.Networks), 1)
+}
+
+func TestReachableFrom(t *testing.T) {
+	p := &Project{
+		Services: Services{
+			"front": {
+				Name:      "front",
+				DependsOn: DependsOnConfig{"api": {Required: true}, "auth": {Required: true}},
+			},
+			"api": {
+				Name:      "api",
+				DependsOn: DependsOnConfig{"db": {Required: true}, "cache": {Required: false}},
+			},
+			"auth": {
+				Name:      "auth",
+				DependsOn: DependsOnConfig{"db": {Required: true}},
+			},
+			"db":    {Name: "db"},
+			"batch": {Name: "batch", DependsOn: DependsOnConfig{"db": {Required: true}}},
+			"ping":  {Name: "ping", DependsOn: DependsOnConfig{"pong": {Required: true}}},
+			"pong":  {Name: "pong", DependsOn: DependsOnConfig{"ping": {Required: true}}},
+		},
+	}
+
+	reachable, err := p.ReachableFrom([]string{"front"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reachable, []string{"api", "auth", "db", "front"})
+
+	reachable, err = p.ReachableFrom([]string{"auth", "batch"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reachable, []string{"auth", "batch", "db"})
+
+	reachable, err = p.ReachableFrom([]string{"ping"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reachable, []string{"ping", "pong"})
+
+	_, err = p.ReachableFrom([]string{"unknown"})
+	assert.Error(t, err, "no such service: unknown, available services are api, auth, batch, db, front, ping, pong: not found")
+
+	p.Services["db"] = ServiceConfig{Name: "db", DependsOn: DependsOnConfig{"storage": {Required: true}}}
+	_, err = p.ReachableFrom([]string{"front"})
+	assert.Error(t, err, `service "db" depends on unknown service "storage"`)
+}
+
+func Test_GetServiceNotFound(t *testing.T) {
+	p := makeProject()
+	_, err := p.GetService("unknown")
+	assert.Error(t, err, "no such service: unknown, available services are service_1, service_2, service_3, service_4, service_5, service_6: not found")
+	assert.Check(t, errdefs.IsNotFoundError(err))
+
+	_, err = (&Project{}).GetService("unknown")
+	assert.Error(t, err, "no such service: unknown: not found")
+}
+
+func Test_GetServiceByContainerName(t *testing.T) {
+	p := makeProject()
+	p.Name = "myproject"
+	service := p.Services["service_2"]
+	service.ContainerName = "custom"
+	p.Services["service_2"] = service
+
+	tests := map[string]string{
+		"custom":                "service_2",
+		"myproject-service_1-1": "service_1",
+		"myproject_service_6_2": "service_6",
+	}
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			service, err := p.GetServiceByContainerName(name)
+			assert.NilError(t, err)
+			assert.Equal(t, service.Name, expected)
+		})
+	}
+
+	for _, name := range []string{"myproject-service_2-1", "myproject-service_1-", "myproject-service_1-x", "service_1"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.GetServiceByContainerName(name)
+			assert.Check(t, errdefs.IsNotFoundError(err))
+		})
+	}
+}
+
 func makeProject() *Project {
 	return &Project{
 		Services: Services{
@@ -371,3 +532,392 @@ func TestServicesWithCapabilities(t *testing.T) {
 	assert.DeepEqual(t, []string{"service_1"}, gpu)
 	assert.DeepEqual(t, []string{"service_1", "service_2"}, tpu)
 }
+
+func TestMarshalYAMLSorted(t *testing.T) {
+	p := &Project{
+		Name: "test",
+		Services: Services{
+			"foo": {
+				Name:    "foo",
+				Image:   "alpine",
+				Command: ShellCommand{"echo", "zz", "aa"},
+				Environment: NewMappingWithEquals([]string{
+					"VAR9=nine",
+					"VAR10=ten",
+					"var_lower=lower",
+					"VAR_A=a",
+				}),
+				Labels: Labels{
+					"com.example.10": "ten",
+					"com.example.9":  "nine",
+				},
+			},
+		},
+	}
+
+	// by default, yaml encoder uses a natural sort order (VAR9 < VAR10)
+	b, err := p.MarshalYAML()
+	assert.NilError(t, err)
+	assert.Check(t, strings.Index(string(b), "VAR9:") < strings.Index(string(b), "VAR10:"))
+
+	b, err = p.MarshalYAMLWithOptions(WithSortedEnvironment(), WithSortedLabels())
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `name: test
+services:
+  foo:
+    command:
+      - echo
+      - zz
+      - aa
+    environment:
+      VAR10: ten
+      VAR9: nine
+      VAR_A: a
+      var_lower: lower
+    image: alpine
+    labels:
+      com.example.10: ten
+      com.example.9: nine
+`)
+}
+
+func TestProjectEqual(t *testing.T) {
+	p1 := &Project{
+		Name:         "test",
+		ComposeFiles: []string{"compose.yaml"},
+		Services: Services{
+			"foo": {
+				Name:        "foo",
+				Image:       "alpine",
+				Command:     ShellCommand{"echo", "hello"},
+				Environment: NewMappingWithEquals([]string{"FOO=foo", "BAR=bar"}),
+				CapAdd:      []string{"NET_ADMIN", "SYS_ADMIN"},
+				Ports: []ServicePortConfig{
+					{Target: 80, Published: "8080"},
+					{Target: 443, Published: "8443"},
+				},
+			},
+		},
+	}
+
+	p2 := &Project{
+		Name:         "test",
+		ComposeFiles: []string{"compose.yaml", "compose.override.yaml"},
+		Services: Services{
+			"foo": {
+				Name:        "foo",
+				Image:       "alpine",
+				Command:     ShellCommand{"echo", "hello"},
+				Environment: NewMappingWithEquals([]string{"BAR=bar", "FOO=foo"}),
+				CapAdd:      []string{"SYS_ADMIN", "NET_ADMIN"},
+				Ports: []ServicePortConfig{
+					{Target: 443, Published: "8443"},
+					{Target: 80, Published: "8080"},
+				},
+			},
+		},
+	}
+	assert.Check(t, p1.Equal(p2))
+	// original order is preserved
+	assert.DeepEqual(t, p2.Services["foo"].CapAdd, []string{"SYS_ADMIN", "NET_ADMIN"})
+
+	p3 := p2.DeepCopy()
+	foo := p3.Services["foo"]
+	foo.Command = ShellCommand{"hello", "echo"}
+	p3.Services["foo"] = foo
+	assert.Check(t, !p1.Equal(p3), "command order is significant")
+
+	assert.Check(t, !p1.Equal(nil))
+	assert.Check(t, (*Project)(nil).Equal(nil))
+}
+
+func TestProjectHash(t *testing.T) {
+	project := func(workingDir string, volumes ...ServiceVolumeConfig) *Project {
+		return &Project{
+			Name:       "test",
+			WorkingDir: workingDir,
+			Services: Services{
+				"foo": {
+					Name:  "foo",
+					Image: "alpine",
+					Build: &BuildConfig{
+						Context:    filepath.Join(workingDir, "foo"),
+						Dockerfile: "Dockerfile",
+					},
+					EnvFiles: []EnvFile{{Path: filepath.Join(workingDir, ".env"), Required: true}},
+					Volumes:  volumes,
+				},
+			},
+			Secrets: Secrets{
+				"token": {File: filepath.Join(workingDir, "token.txt")},
+			},
+		}
+	}
+	dir1 := filepath.Join(string(filepath.Separator), "home", "user", "project")
+	dir2 := filepath.Join(string(filepath.Separator), "ci", "checkout")
+
+	h1, err := project(dir1).Hash()
+	assert.NilError(t, err)
+	assert.Equal(t, len(h1), 64)
+	h2, err := project(dir2).Hash()
+	assert.NilError(t, err)
+	assert.Equal(t, h1, h2, "hash must not depend on project location")
+
+	// original project is left untouched
+	p := project(dir1)
+	_, err = p.Hash()
+	assert.NilError(t, err)
+	assert.Equal(t, p.Services["foo"].Build.Context, filepath.Join(dir1, "foo"))
+
+	// paths outside of working directory are significant
+	outside := filepath.Join(string(filepath.Separator), "data")
+	h1, err = project(dir1, ServiceVolumeConfig{Type: VolumeTypeBind, Source: outside, Target: "/data"}).Hash()
+	assert.NilError(t, err)
+	h2, err = project(dir1, ServiceVolumeConfig{Type: VolumeTypeBind, Source: filepath.Join(dir1, "data"), Target: "/data"}).Hash()
+	assert.NilError(t, err)
+	assert.Check(t, h1 != h2)
+
+	h3, err := project(dir2, ServiceVolumeConfig{Type: VolumeTypeBind, Source: outside, Target: "/data"}).Hash()
+	assert.NilError(t, err)
+	assert.Equal(t, h1, h3)
+}
+
+func TestWithServicesEnvironmentMaterialized(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.env")
+	second := filepath.Join(dir, "second.env")
+	assert.NilError(t, os.WriteFile(first, []byte("FILE_ONLY=first\nOVERRIDDEN_BY_FILE=first\nOVERRIDDEN_BY_ENV=file\nUNRESOLVED=file\nRESOLVED=file\n"), 0o600))
+	assert.NilError(t, os.WriteFile(second, []byte("OVERRIDDEN_BY_FILE=second\nFROM_PREVIOUS=${FILE_ONLY}\nFROM_LOOKUP=${HOST}\n"), 0o600))
+
+	p := &Project{
+		Services: Services{
+			"app": {
+				Name: "app",
+				EnvFiles: []EnvFile{
+					{Path: first, Required: true},
+					{Path: second, Required: true},
+					{Path: filepath.Join(dir, "missing.env"), Required: false},
+				},
+				Environment: NewMappingWithEquals([]string{
+					"OVERRIDDEN_BY_ENV=env",
+					"EMPTY=",
+					"UNRESOLVED",
+					"RESOLVED",
+					"HOST",
+					"NOT_SET",
+				}),
+			},
+		},
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := map[string]string{"RESOLVED": "host", "HOST": "host"}[key]
+		return v, ok
+	}
+
+	resolved, err := p.WithServicesEnvironmentMaterialized(lookup, true)
+	assert.NilError(t, err)
+	app := resolved.Services["app"]
+	assert.DeepEqual(t, app.Environment.ToMapping(nil), Mapping{
+		"FILE_ONLY":          "first",
+		"OVERRIDDEN_BY_FILE": "second",
+		"OVERRIDDEN_BY_ENV":  "env",
+		"UNRESOLVED":         "file",
+		"RESOLVED":           "host",
+		"FROM_PREVIOUS":      "first",
+		"FROM_LOOKUP":        "host",
+		"HOST":               "host",
+		"EMPTY":              "",
+	})
+	for k, v := range app.Environment {
+		assert.Assert(t, v != nil, k)
+	}
+	assert.Check(t, app.EnvFiles == nil)
+
+	// source project is left untouched
+	assert.Check(t, p.Services["app"].Environment["RESOLVED"] == nil)
+	assert.Equal(t, len(p.Services["app"].EnvFiles), 3)
+
+	p.Services["app"] = ServiceConfig{
+		Name:     "app",
+		EnvFiles: []EnvFile{{Path: filepath.Join(dir, "missing.env"), Required: true}},
+	}
+	_, err = p.WithServicesEnvironmentMaterialized(nil, false)
+	assert.ErrorContains(t, err, "missing.env not found")
+}
+
+func TestProjectDeepCopy(t *testing.T) {
+	replicas := 2
+	p := &Project{
+		Name: "test",
+		Services: Services{
+			"app": {
+				Name:        "app",
+				Environment: NewMappingWithEquals([]string{"FOO=bar"}),
+				Ports:       []ServicePortConfig{{Target: 80}},
+				Deploy:      &DeployConfig{Replicas: &replicas},
+				Extensions:  Extensions{"x-meta": map[string]any{"owner": "team"}},
+			},
+		},
+		Extensions: Extensions{"x-project": []any{"a"}},
+	}
+
+	c := p.DeepCopy()
+	assert.DeepEqual(t, c, p)
+
+	app := c.Services["app"]
+	*app.Environment["FOO"] = "changed"
+	app.Ports[0].Target = 8080
+	*app.Deploy.Replicas = 3
+	app.Extensions["x-meta"].(map[string]any)["owner"] = "other"
+	c.Extensions["x-project"].([]any)[0] = "b"
+
+	original := p.Services["app"]
+	assert.Equal(t, *original.Environment["FOO"], "bar")
+	assert.Equal(t, original.Ports[0].Target, uint32(80))
+	assert.Equal(t, *original.Deploy.Replicas, 2)
+	assert.Equal(t, original.Extensions["x-meta"].(map[string]any)["owner"], "team")
+	assert.Equal(t, p.Extensions["x-project"].([]any)[0], "a")
+}
+
+func TestProjectResourceNames(t *testing.T) {
+	p := &Project{
+		Services: Services{"web": {}, "db": {}, "cache": {}},
+		Networks: Networks{"front": {}, "back": {}},
+		Volumes:  Volumes{"data": {}, "cache": {}},
+		Secrets:  Secrets{"token": {}, "cert": {}},
+		Configs:  Configs{"nginx": {}, "app": {}},
+	}
+	assert.DeepEqual(t, p.ServiceNames(), []string{"cache", "db", "web"})
+	assert.DeepEqual(t, p.NetworkNames(), []string{"back", "front"})
+	assert.DeepEqual(t, p.VolumeNames(), []string{"cache", "data"})
+	assert.DeepEqual(t, p.SecretNames(), []string{"cert", "token"})
+	assert.DeepEqual(t, p.ConfigNames(), []string{"app", "nginx"})
+	assert.Check(t, p.DisabledServiceNames() == nil)
+}
+
+func TestProjectAllProfiles(t *testing.T) {
+	p := &Project{
+		Services: Services{
+			"web":   {Name: "web"},
+			"debug": {Name: "debug", Profiles: []string{"debug", "dev"}},
+		},
+		DisabledServices: Services{
+			"test": {Name: "test", Profiles: []string{"test", "dev"}},
+		},
+	}
+	assert.DeepEqual(t, p.AllProfiles(), []string{"debug", "dev", "test"})
+
+	p, err := p.WithProfiles([]string{"test"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.AllProfiles(), []string{"debug", "dev", "test"})
+
+	assert.Check(t, (&Project{Services: Services{"web": {}}}).AllProfiles() == nil)
+}
+
+func TestOrderedServices(t *testing.T) {
+	p := &Project{
+		Services: Services{
+			"web":   {Name: "web", DependsOn: DependsOnConfig{"db": {}}},
+			"db":    {Name: "db"},
+			"cache": {Name: "cache", DependsOn: DependsOnConfig{"db": {}}},
+			"api":   {Name: "api", DependsOn: DependsOnConfig{"db": {}}},
+		},
+	}
+	var names []string
+	for _, s := range p.OrderedServices() {
+		names = append(names, s.Name)
+	}
+	assert.DeepEqual(t, names, []string{"api", "cache", "db", "web"})
+	assert.DeepEqual(t, p.Services["db"].GetDependents(p), []string{"api", "cache", "web"})
+	assert.Equal(t, len((&Project{}).OrderedServices()), 0)
+}
+
+func TestWithServicesTransform(t *testing.T) {
+	p := &Project{
+		Services: Services{
+			"web": {Name: "web", Image: "nginx"},
+			"db":  {Name: "db", Image: "docker.io/library/postgres"},
+		},
+	}
+	transformed, err := p.WithServicesTransform(func(s ServiceConfig) (ServiceConfig, error) {
+		s.Image = "registry.example.com/" + strings.TrimPrefix(s.Image, "docker.io/library/")
+		s.Labels = s.Labels.Add("sidecar", "true")
+		return s, nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, transformed.Services["web"].Image, "registry.example.com/nginx")
+	assert.Equal(t, transformed.Services["db"].Image, "registry.example.com/postgres")
+	assert.DeepEqual(t, transformed.Services["db"].Labels, Labels{"sidecar": "true"})
+	// original project is unchanged
+	assert.Equal(t, p.Services["web"].Image, "nginx")
+	assert.Check(t, p.Services["web"].Labels == nil)
+
+	_, err = p.WithServicesTransform(func(s ServiceConfig) (ServiceConfig, error) {
+		return s, fmt.Errorf("can't rewrite %s", s.Image)
+	})
+	assert.Error(t, err, "services.db: can't rewrite docker.io/library/postgres\nservices.web: can't rewrite nginx")
+}
+
+func TestProjectEncode(t *testing.T) {
+	p := &Project{
+		Name: "encode",
+		Services: Services{
+			"web": ServiceConfig{Name: "web", Image: "nginx"},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NilError(t, p.Encode(&buf, "yaml"))
+	expected, err := p.MarshalYAML()
+	assert.NilError(t, err)
+	assert.Equal(t, buf.String(), string(expected))
+
+	buf.Reset()
+	assert.NilError(t, p.Encode(&buf, "json"))
+	var decoded map[string]any
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, decoded["name"], "encode")
+	assert.Assert(t, strings.HasSuffix(buf.String(), "}\n"))
+
+	assert.Error(t, p.Encode(&buf, "toml"), `unsupported format "toml", expected either "yaml" or "json"`)
+}
+
+func TestWithContainerNames(t *testing.T) {
+	scale := 2
+	p := &Project{
+		Name: "myproject",
+		Services: Services{
+			"web":    ServiceConfig{Name: "web"},
+			"named":  ServiceConfig{Name: "named", ContainerName: "explicit"},
+			"scaled": ServiceConfig{Name: "scaled", Scale: &scale},
+		},
+	}
+
+	named, err := p.WithContainerNames("${project}-${service}-${index}")
+	assert.NilError(t, err)
+	assert.Equal(t, named.Services["web"].ContainerName, "myproject-web-1")
+	assert.Equal(t, named.Services["named"].ContainerName, "explicit")
+	assert.Equal(t, named.Services["scaled"].ContainerName, "")
+	assert.Equal(t, p.Services["web"].ContainerName, "")
+
+	_, err = p.WithContainerNames("${project}/${service}")
+	assert.Error(t, err, `services.web: invalid container name "myproject/web", only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed: invalid compose project`)
+}
+
+func TestContainerNameFor(t *testing.T) {
+	scaled := ServiceConfig{Name: "scaled"}
+	scaled.SetScale(2)
+	for index, expected := range map[int]string{1: "myproject-scaled-1", 2: "myproject-scaled-2"} {
+		name, err := scaled.ContainerNameFor("${project}-${service}-${index}", "myproject", index)
+		assert.NilError(t, err)
+		assert.Equal(t, name, expected)
+	}
+
+	named := ServiceConfig{Name: "named", ContainerName: "explicit"}
+	name, err := named.ContainerNameFor("${project}-${service}-${index}", "myproject", 1)
+	assert.NilError(t, err)
+	assert.Equal(t, name, "explicit")
+
+	_, err = scaled.ContainerNameFor("${project}/${service}", "myproject", 1)
+	assert.Error(t, err, `invalid container name "myproject/scaled", only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed: invalid compose project`)
+}
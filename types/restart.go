@@ -0,0 +1,88 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deployRestartConditions maps `deploy.restart_policy.condition` values to their `restart` equivalent
+var deployRestartConditions = map[string]string{
+	"none":       RestartPolicyNo,
+	"on-failure": RestartPolicyOnFailure,
+	"any":        RestartPolicyAlways,
+}
+
+// ParseRestartPolicy parses the `restart` short syntax, which is one of `no`, `always`, `unless-stopped`
+// or `on-failure[:max-retries]`
+func ParseRestartPolicy(restart string) (RestartPolicy, error) {
+	condition, retries, hasRetries := strings.Cut(restart, ":")
+	switch condition {
+	case RestartPolicyNo, RestartPolicyAlways, RestartPolicyUnlessStopped:
+		if !hasRetries {
+			return RestartPolicy{Condition: condition}, nil
+		}
+	case RestartPolicyOnFailure:
+		if !hasRetries {
+			return RestartPolicy{Condition: condition}, nil
+		}
+		maxAttempts, err := strconv.ParseUint(retries, 10, 64)
+		if err != nil {
+			return RestartPolicy{}, fmt.Errorf("invalid restart policy %q, max-retries must be a non-negative integer", restart)
+		}
+		return RestartPolicy{Condition: condition, MaxAttempts: &maxAttempts}, nil
+	}
+	return RestartPolicy{}, fmt.Errorf("invalid restart policy %q, must be one of no, always, unless-stopped or on-failure[:max-retries]", restart)
+}
+
+// GetRestartPolicy returns the service restart policy, as set by `restart` or `deploy.restart_policy`, the former
+// taking precedence. Condition uses the `restart` vocabulary, i.e. `no`, `always`, `unless-stopped` or `on-failure`.
+// It returns nil if the service doesn't declare a restart policy.
+func (s ServiceConfig) GetRestartPolicy() (*RestartPolicy, error) {
+	if s.Restart != "" {
+		policy, err := ParseRestartPolicy(s.Restart)
+		if err != nil {
+			return nil, err
+		}
+		return &policy, nil
+	}
+	if s.Deploy == nil || s.Deploy.RestartPolicy == nil {
+		return nil, nil
+	}
+	policy := *s.Deploy.RestartPolicy
+	if policy.Condition == "" {
+		policy.Condition = "any"
+	}
+	condition, err := ParseDeployRestartCondition(policy.Condition)
+	if err != nil {
+		return nil, err
+	}
+	policy.Condition = condition
+	return &policy, nil
+}
+
+// ParseDeployRestartCondition parses a `deploy.restart_policy.condition`, which is one of `none`, `on-failure` or
+// `any`, and returns its `restart` equivalent
+func ParseDeployRestartCondition(condition string) (string, error) {
+	restart, ok := deployRestartConditions[condition]
+	if !ok {
+		return "", fmt.Errorf("invalid restart_policy condition %q, must be one of none, on-failure or any", condition)
+	}
+	return restart, nil
+}
@@ -16,23 +16,28 @@
 
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 // StringList is a type for fields that can be a string or list of strings
 type StringList []string
 
 func (l *StringList) DecodeMapstructure(value interface{}) error {
 	switch v := value.(type) {
-	case string:
-		*l = []string{v}
 	case []interface{}:
-		list := make([]string, len(v))
-		for i, e := range v {
-			list[i] = e.(string)
+		list, err := toStrings(v)
+		if err != nil {
+			return err
 		}
 		*l = list
 	default:
-		return fmt.Errorf("invalid type %T for string list", value)
+		s, err := toString(value)
+		if err != nil {
+			return fmt.Errorf("invalid type %T for string list", value)
+		}
+		*l = []string{s}
 	}
 	return nil
 }
@@ -42,16 +47,60 @@ type StringOrNumberList []string
 
 func (l *StringOrNumberList) DecodeMapstructure(value interface{}) error {
 	switch v := value.(type) {
-	case string:
-		*l = []string{v}
 	case []interface{}:
 		list := make([]string, len(v))
 		for i, e := range v {
-			list[i] = fmt.Sprint(e)
+			s, err := toStringOrBool(e)
+			if err != nil {
+				return err
+			}
+			list[i] = s
 		}
 		*l = list
 	default:
-		return fmt.Errorf("invalid type %T for string list", value)
+		s, err := toStringOrBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid type %T for string list", value)
+		}
+		*l = []string{s}
 	}
 	return nil
 }
+
+// toStringOrBool converts a scalar to a string like toString does, also accepting booleans
+func toStringOrBool(value interface{}) (string, error) {
+	if b, ok := value.(bool); ok {
+		return strconv.FormatBool(b), nil
+	}
+	return toString(value)
+}
+
+// toStrings converts a list of scalars to strings, see toString
+func toStrings(values []interface{}) ([]string, error) {
+	list := make([]string, len(values))
+	for i, e := range values {
+		s, err := toString(e)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = s
+	}
+	return list, nil
+}
+
+// toString converts a scalar to a string, so that numbers, typically unquoted ports, can be used where a string is
+// expected
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("invalid type %T, expected a string or a number", value)
+	}
+}
@@ -19,11 +19,17 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"path"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/compose-spec/compose-go/v2/errdefs"
+	"github.com/compose-spec/compose-go/v2/utils"
 	"github.com/docker/go-connections/nat"
 	"github.com/mitchellh/copystructure"
+	"gopkg.in/yaml.v3"
 )
 
 // ServiceConfig is the configuration of one service
@@ -81,6 +87,7 @@ type ServiceConfig struct {
 	Extends         *ExtendsConfig                   `yaml:"extends,omitempty" json:"extends,omitempty"`
 	ExternalLinks   []string                         `yaml:"external_links,omitempty" json:"external_links,omitempty"`
 	ExtraHosts      HostsList                        `yaml:"extra_hosts,omitempty" json:"extra_hosts,omitempty"`
+	Gpus            []DeviceRequest                  `yaml:"gpus,omitempty" json:"gpus,omitempty"`
 	GroupAdd        []string                         `yaml:"group_add,omitempty" json:"group_add,omitempty"`
 	Hostname        string                           `yaml:"hostname,omitempty" json:"hostname,omitempty"`
 	HealthCheck     *HealthCheckConfig               `yaml:"healthcheck,omitempty" json:"healthcheck,omitempty"`
@@ -90,6 +97,7 @@ type ServiceConfig struct {
 	Isolation       string                           `yaml:"isolation,omitempty" json:"isolation,omitempty"`
 	Labels          Labels                           `yaml:"labels,omitempty" json:"labels,omitempty"`
 	CustomLabels    Labels                           `yaml:"-" json:"-"`
+	LabelFiles      StringList                       `yaml:"label_file,omitempty" json:"label_file,omitempty"`
 	Links           []string                         `yaml:"links,omitempty" json:"links,omitempty"`
 	Logging         *LoggingConfig                   `yaml:"logging,omitempty" json:"logging,omitempty"`
 	LogDriver       string                           `yaml:"log_driver,omitempty" json:"log_driver,omitempty"`
@@ -107,7 +115,7 @@ type ServiceConfig struct {
 	Pid             string                           `yaml:"pid,omitempty" json:"pid,omitempty"`
 	PidsLimit       int64                            `yaml:"pids_limit,omitempty" json:"pids_limit,omitempty"`
 	Platform        string                           `yaml:"platform,omitempty" json:"platform,omitempty"`
-	Ports           []ServicePortConfig              `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Ports           Ports                            `yaml:"ports,omitempty" json:"ports,omitempty"`
 	Privileged      bool                             `yaml:"privileged,omitempty" json:"privileged,omitempty"`
 	PullPolicy      string                           `yaml:"pull_policy,omitempty" json:"pull_policy,omitempty"`
 	ReadOnly        bool                             `yaml:"read_only,omitempty" json:"read_only,omitempty"`
@@ -141,9 +149,42 @@ func (s ServiceConfig) MarshalYAML() (interface{}, error) {
 	type t ServiceConfig
 	value := t(s)
 	value.Name = "" // set during map to slice conversion, not part of the yaml representation
+	if len(s.Networks) > 0 && !hasNetworkOptions(s.Networks) {
+		// networks without options are rendered using the short list syntax
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "networks" {
+				var names yaml.Node
+				if err := names.Encode(utils.MapKeys(s.Networks)); err != nil {
+					return nil, err
+				}
+				node.Content[i+1] = &names
+			}
+		}
+		return &node, nil
+	}
 	return value, nil
 }
 
+// hasNetworkOptions returns true if any of the service networks is declared with options
+func hasNetworkOptions(networks map[string]*ServiceNetworkConfig) bool {
+	for _, n := range networks {
+		if n != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON makes ServiceConfig implement json.Marshaler
+func (s ServiceConfig) MarshalJSON() ([]byte, error) {
+	type t ServiceConfig
+	return marshalJSONWithExtensions(t(s), s.Extensions)
+}
+
 // NetworksByPriority return the service networks IDs sorted according to Priority
 func (s *ServiceConfig) NetworksByPriority() []string {
 	type key struct {
@@ -174,6 +215,19 @@ func (s *ServiceConfig) NetworksByPriority() []string {
 	return sorted
 }
 
+// EffectiveCommand returns the arguments a service container runs, as Docker combines entrypoint and command.
+// When entrypoint is set, even as an empty list, it replaces the image ENTRYPOINT and command is appended to it.
+// When entrypoint is unset, the image ENTRYPOINT (unknown to the compose model) would run with command as
+// arguments, so only command is returned. A nil result means both are unset and the image defaults apply.
+func (s ServiceConfig) EffectiveCommand() ShellCommand {
+	if s.Entrypoint == nil {
+		return s.Command
+	}
+	command := make(ShellCommand, 0, len(s.Entrypoint)+len(s.Command))
+	command = append(command, s.Entrypoint...)
+	return append(command, s.Command...)
+}
+
 func (s *ServiceConfig) GetScale() int {
 	if s.Scale != nil {
 		return *s.Scale
@@ -192,6 +246,22 @@ func (s *ServiceConfig) SetScale(scale int) {
 	}
 }
 
+// containerNamePattern matches container names accepted by Docker
+var containerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// ContainerNameFor returns the name of the service container with the given index, starting at 1. When the service
+// doesn't declare a container_name, it is derived from pattern by replacing `${project}`, `${service}` and `${index}`
+func (s ServiceConfig) ContainerNameFor(pattern string, project string, index int) (string, error) {
+	if s.ContainerName != "" {
+		return s.ContainerName, nil
+	}
+	name := strings.NewReplacer("${project}", project, "${service}", s.Name, "${index}", strconv.Itoa(index)).Replace(pattern)
+	if !containerNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid container name %q, only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed: %w", name, errdefs.ErrInvalid)
+	}
+	return name, nil
+}
+
 func (s *ServiceConfig) deepCopy() *ServiceConfig {
 	instance, err := copystructure.Copy(s)
 	if err != nil {
@@ -250,7 +320,7 @@ func (s ServiceConfig) GetDependencies() []string {
 // GetDependents retrieves all services which depend on this service
 func (s ServiceConfig) GetDependents(p *Project) []string {
 	var dependent []string
-	for _, service := range p.Services {
+	for _, service := range p.OrderedServices() {
 		for name := range service.DependsOn {
 			if name == s.Name {
 				dependent = append(dependent, service.Name)
@@ -268,6 +338,7 @@ type BuildConfig struct {
 	Args               MappingWithEquals         `yaml:"args,omitempty" json:"args,omitempty"`
 	SSH                SSHConfig                 `yaml:"ssh,omitempty" json:"ssh,omitempty"`
 	Labels             Labels                    `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations        Mapping                   `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 	CacheFrom          StringList                `yaml:"cache_from,omitempty" json:"cache_from,omitempty"`
 	CacheTo            StringList                `yaml:"cache_to,omitempty" json:"cache_to,omitempty"`
 	NoCache            bool                      `yaml:"no_cache,omitempty" json:"no_cache,omitempty"`
@@ -287,6 +358,12 @@ type BuildConfig struct {
 	Extensions Extensions `yaml:"#extensions,inline,omitempty" json:"-"`
 }
 
+// MarshalJSON makes BuildConfig implement json.Marshaler
+func (b BuildConfig) MarshalJSON() ([]byte, error) {
+	type t BuildConfig
+	return marshalJSONWithExtensions(t(b), b.Extensions)
+}
+
 // BlkioConfig define blkio config
 type BlkioConfig struct {
 	Weight          uint16           `yaml:"weight,omitempty" json:"weight,omitempty"`
@@ -598,6 +675,20 @@ type ServiceConfigObjConfig FileReferenceConfig
 // ServiceSecretConfig is the secret configuration for a service
 type ServiceSecretConfig FileReferenceConfig
 
+// EffectiveTarget returns the path the secret is mounted to inside the service container.
+// Without an explicit target, secret is mounted as /run/secrets/<source>, and a relative target
+// is resolved within /run/secrets
+func (s ServiceSecretConfig) EffectiveTarget() string {
+	target := s.Target
+	if target == "" {
+		target = s.Source
+	}
+	if path.IsAbs(target) {
+		return target
+	}
+	return path.Join("/run/secrets", target)
+}
+
 // UlimitsConfig the ulimit configuration
 type UlimitsConfig struct {
 	Single int `yaml:"single,omitempty" json:"single,omitempty"`
@@ -669,6 +760,12 @@ type NetworkConfig struct {
 	Extensions Extensions `yaml:"#extensions,inline,omitempty" json:"-"`
 }
 
+// MarshalJSON makes NetworkConfig implement json.Marshaler
+func (n NetworkConfig) MarshalJSON() ([]byte, error) {
+	type t NetworkConfig
+	return marshalJSONWithExtensions(t(n), n.Extensions)
+}
+
 // IPAMConfig for a network
 type IPAMConfig struct {
 	Driver     string      `yaml:"driver,omitempty" json:"driver,omitempty"`
@@ -695,6 +792,12 @@ type VolumeConfig struct {
 	Extensions Extensions `yaml:"#extensions,inline,omitempty" json:"-"`
 }
 
+// MarshalJSON makes VolumeConfig implement json.Marshaler
+func (v VolumeConfig) MarshalJSON() ([]byte, error) {
+	type t VolumeConfig
+	return marshalJSONWithExtensions(t(v), v.Extensions)
+}
+
 // External identifies a Volume or Network as a reference to a resource that is
 // not managed, and should already exist.
 type External bool
@@ -746,12 +849,26 @@ type ExtendsConfig struct {
 	Service string `yaml:"service,omitempty" json:"service,omitempty"`
 }
 
+// ExtendsSourceExtension is the service extension recording the absolute path of the compose file a service was
+// extended from, when requested by loader options
+const ExtendsSourceExtension = "x-extends-source"
+
 // SecretConfig for a secret
 type SecretConfig FileObjectConfig
 
+// MarshalJSON makes SecretConfig implement json.Marshaler
+func (s SecretConfig) MarshalJSON() ([]byte, error) {
+	return marshalJSONWithExtensions(FileObjectConfig(s), s.Extensions)
+}
+
 // ConfigObjConfig is the config for the swarm "Config" object
 type ConfigObjConfig FileObjectConfig
 
+// MarshalJSON makes ConfigObjConfig implement json.Marshaler
+func (c ConfigObjConfig) MarshalJSON() ([]byte, error) {
+	return marshalJSONWithExtensions(FileObjectConfig(c), c.Extensions)
+}
+
 type IncludeConfig struct {
 	Path             StringList `yaml:"path,omitempty" json:"path,omitempty"`
 	ProjectDirectory string     `yaml:"project_directory,omitempty" json:"project_directory,omitempty"`
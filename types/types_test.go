@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -232,6 +233,24 @@ func TestExtension(t *testing.T) {
 	assert.Check(t, ok == false)
 }
 
+func TestMarshalJSONExtensions(t *testing.T) {
+	s := ServiceConfig{
+		Image: "foo",
+		Extensions: Extensions{
+			"x-zot": []string{"qix"},
+			"x-bar": "baz",
+		},
+	}
+	b, err := json.Marshal(s)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"command":null,"entrypoint":null,"image":"foo","x-bar":"baz","x-zot":["qix"]}`)
+
+	v := VolumeConfig{Extensions: Extensions{"x-foo": "bar"}}
+	b, err = json.Marshal(v)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"x-foo":"bar"}`)
+}
+
 func TestNewMapping(t *testing.T) {
 	m := NewMapping([]string{
 		"FOO=BAR",
@@ -251,6 +270,32 @@ func TestNewMapping(t *testing.T) {
 	assert.Check(t, mw["QIX"] == nil)
 }
 
+func TestMappingWithEqualsToMapping(t *testing.T) {
+	mw := NewMappingWithEquals([]string{
+		"FOO=BAR",
+		"ZOT=",
+		"QIX",
+		"UNSET",
+	})
+	lookup := func(k string) (string, bool) {
+		if k == "QIX" {
+			return "from lookup", true
+		}
+		return "", false
+	}
+	assert.DeepEqual(t, mw.ToMapping(lookup), Mapping{
+		"FOO": "BAR",
+		"ZOT": "",
+		"QIX": "from lookup",
+	})
+	// original mapping is left unchanged
+	assert.Check(t, mw["QIX"] == nil)
+	assert.DeepEqual(t, mw.ToMapping(nil), Mapping{
+		"FOO": "BAR",
+		"ZOT": "",
+	})
+}
+
 func TestNetworksByPriority(t *testing.T) {
 	s := ServiceConfig{
 		Networks: map[string]*ServiceNetworkConfig{
@@ -382,3 +427,223 @@ func TestMappingValues(t *testing.T) {
 	})
 	assert.DeepEqual(t, mapping.Values(), values)
 }
+
+func TestServiceSecretEffectiveTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   ServiceSecretConfig
+		expected string
+	}{
+		{
+			name:     "default target",
+			secret:   ServiceSecretConfig{Source: "db_password"},
+			expected: "/run/secrets/db_password",
+		},
+		{
+			name:     "explicit target",
+			secret:   ServiceSecretConfig{Source: "db_password", Target: "/etc/db/password"},
+			expected: "/etc/db/password",
+		},
+		{
+			name:     "relative target",
+			secret:   ServiceSecretConfig{Source: "db_password", Target: "password"},
+			expected: "/run/secrets/password",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.secret.EffectiveTarget(), tt.expected)
+		})
+	}
+}
+
+func TestDeviceCountMarshal(t *testing.T) {
+	b, err := json.Marshal(DeviceRequest{Count: AllDevices})
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"count":"all"}`)
+	b, err = json.Marshal(DeviceRequest{Count: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"count":2}`)
+
+	y, err := yaml.Marshal(DeviceRequest{Count: AllDevices})
+	assert.NilError(t, err)
+	assert.Equal(t, string(y), "count: all\n")
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	five := uint64(5)
+	tests := []struct {
+		restart  string
+		expected RestartPolicy
+		err      string
+	}{
+		{restart: "no", expected: RestartPolicy{Condition: RestartPolicyNo}},
+		{restart: "always", expected: RestartPolicy{Condition: RestartPolicyAlways}},
+		{restart: "unless-stopped", expected: RestartPolicy{Condition: RestartPolicyUnlessStopped}},
+		{restart: "on-failure", expected: RestartPolicy{Condition: RestartPolicyOnFailure}},
+		{restart: "on-failure:5", expected: RestartPolicy{Condition: RestartPolicyOnFailure, MaxAttempts: &five}},
+		{restart: "on-failure:-1", err: `invalid restart policy "on-failure:-1", max-retries must be a non-negative integer`},
+		{restart: "always:3", err: `invalid restart policy "always:3", must be one of no, always, unless-stopped or on-failure[:max-retries]`},
+		{restart: "sometimes", err: `invalid restart policy "sometimes", must be one of no, always, unless-stopped or on-failure[:max-retries]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.restart, func(t *testing.T) {
+			policy, err := ParseRestartPolicy(tt.restart)
+			if tt.err != "" {
+				assert.Error(t, err, tt.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, policy, tt.expected)
+		})
+	}
+}
+
+func TestGetRestartPolicy(t *testing.T) {
+	three := uint64(3)
+	policy, err := ServiceConfig{}.GetRestartPolicy()
+	assert.NilError(t, err)
+	assert.Check(t, policy == nil)
+
+	policy, err = ServiceConfig{
+		Deploy: &DeployConfig{RestartPolicy: &RestartPolicy{Condition: "on-failure", MaxAttempts: &three}},
+	}.GetRestartPolicy()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *policy, RestartPolicy{Condition: RestartPolicyOnFailure, MaxAttempts: &three})
+
+	policy, err = ServiceConfig{
+		Deploy: &DeployConfig{RestartPolicy: &RestartPolicy{}},
+	}.GetRestartPolicy()
+	assert.NilError(t, err)
+	assert.Equal(t, policy.Condition, RestartPolicyAlways)
+
+	policy, err = ServiceConfig{
+		Restart: "no",
+		Deploy:  &DeployConfig{RestartPolicy: &RestartPolicy{Condition: "any"}},
+	}.GetRestartPolicy()
+	assert.NilError(t, err)
+	assert.Equal(t, policy.Condition, RestartPolicyNo)
+}
+
+func TestShellCommandString(t *testing.T) {
+	tests := []struct {
+		command  ShellCommand
+		expected string
+	}{
+		{command: nil, expected: ""},
+		{command: ShellCommand{"echo", "hello"}, expected: "echo hello"},
+		{command: ShellCommand{"echo", "hello world"}, expected: "echo 'hello world'"},
+		{command: ShellCommand{"echo", ""}, expected: "echo ''"},
+		{command: ShellCommand{"sh", "-c", `echo "it's $HOME"`}, expected: `sh -c 'echo "it'\''s $HOME"'`},
+		{command: ShellCommand{"--port=8080", "/path/to/file"}, expected: "--port=8080 /path/to/file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.command.String(), tt.expected)
+			if len(tt.command) == 0 {
+				return
+			}
+			var parsed ShellCommand
+			assert.NilError(t, parsed.DecodeMapstructure(tt.expected))
+			assert.DeepEqual(t, parsed, tt.command)
+		})
+	}
+}
+
+func TestEffectiveCommand(t *testing.T) {
+	assert.Check(t, ServiceConfig{}.EffectiveCommand() == nil)
+	assert.DeepEqual(t, ServiceConfig{
+		Command: ShellCommand{"serve"},
+	}.EffectiveCommand(), ShellCommand{"serve"})
+	assert.DeepEqual(t, ServiceConfig{
+		Entrypoint: ShellCommand{"/entrypoint.sh", "--verbose"},
+		Command:    ShellCommand{"serve", "--port", "80"},
+	}.EffectiveCommand(), ShellCommand{"/entrypoint.sh", "--verbose", "serve", "--port", "80"})
+	// an empty entrypoint resets the image ENTRYPOINT
+	assert.DeepEqual(t, ServiceConfig{
+		Entrypoint: ShellCommand{},
+		Command:    ShellCommand{"serve"},
+	}.EffectiveCommand(), ShellCommand{"serve"})
+	assert.DeepEqual(t, ServiceConfig{
+		Entrypoint: ShellCommand{},
+	}.EffectiveCommand(), ShellCommand{})
+}
+
+func TestDurationRoundTrip(t *testing.T) {
+	var hc HealthCheckConfig
+	err := yaml.Unmarshal([]byte("interval: 90s\ntimeout: 0s\n"), &hc)
+	assert.NilError(t, err)
+	assert.Equal(t, *hc.Interval, Duration(90*time.Second))
+	assert.Equal(t, *hc.Timeout, Duration(0))
+	assert.Check(t, hc.StartPeriod == nil, "unset duration must be distinguished from zero")
+
+	b, err := yaml.Marshal(hc)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "timeout: 0s\ninterval: 1m30s\n")
+
+	b, err = json.Marshal(hc)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"timeout":"0s","interval":"1m30s"}`)
+
+	var decoded HealthCheckConfig
+	assert.NilError(t, json.Unmarshal(b, &decoded))
+	assert.DeepEqual(t, decoded, hc)
+
+	err = yaml.Unmarshal([]byte("interval: 1x\n"), &hc)
+	assert.ErrorContains(t, err, `time: unknown unit "x" in duration "1x"`)
+	err = json.Unmarshal([]byte(`{"interval":"soon"}`), &hc)
+	assert.ErrorContains(t, err, `time: invalid duration "soon"`)
+}
+
+func TestDecodeNumericScalars(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected []string
+	}{
+		{name: "string", value: "8080", expected: []string{"8080"}},
+		{name: "integer", value: 8080, expected: []string{"8080"}},
+		{name: "float", value: 1.5, expected: []string{"1.5"}},
+		{name: "mixed list", value: []interface{}{8080, "8081", uint64(8082), 0.25}, expected: []string{"8080", "8081", "8082", "0.25"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expose StringOrNumberList
+			assert.NilError(t, expose.DecodeMapstructure(tt.value))
+			assert.DeepEqual(t, []string(expose), tt.expected)
+
+			var dns StringList
+			assert.NilError(t, dns.DecodeMapstructure(tt.value))
+			assert.DeepEqual(t, []string(dns), tt.expected)
+		})
+	}
+
+	var cmd ShellCommand
+	assert.NilError(t, cmd.DecodeMapstructure([]interface{}{"sleep", 10, 0.5}))
+	assert.DeepEqual(t, cmd, ShellCommand{"sleep", "10", "0.5"})
+
+	var expose StringOrNumberList
+	assert.NilError(t, expose.DecodeMapstructure([]interface{}{"a", true, 1}))
+	assert.DeepEqual(t, expose, StringOrNumberList{"a", "true", "1"})
+	assert.NilError(t, expose.DecodeMapstructure(false))
+	assert.DeepEqual(t, expose, StringOrNumberList{"false"})
+
+	var list StringList
+	assert.ErrorContains(t, list.DecodeMapstructure([]interface{}{"a", true}), "invalid type bool, expected a string or a number")
+	assert.ErrorContains(t, list.DecodeMapstructure(true), "invalid type bool for string list")
+}
+
+func TestMappingOverrideWith(t *testing.T) {
+	base := Mapping{"FOO": "foo", "BAR": "bar"}
+	merged := base.OverrideWith(Mapping{"BAR": "override", "QIX": "qix"})
+	assert.DeepEqual(t, merged, Mapping{"FOO": "foo", "BAR": "override", "QIX": "qix"})
+	assert.DeepEqual(t, base, Mapping{"FOO": "foo", "BAR": "bar"})
+}
+
+func TestMappingWithEqualsOverrideWith(t *testing.T) {
+	foo, bar, override := "foo", "bar", "override"
+	base := MappingWithEquals{"FOO": &foo, "BAR": &bar, "INHERITED": nil}
+	merged := base.OverrideWith(MappingWithEquals{"FOO": nil, "BAR": &override, "INHERITED": &override})
+	assert.DeepEqual(t, merged, MappingWithEquals{"FOO": nil, "BAR": &override, "INHERITED": &override})
+	assert.DeepEqual(t, base, MappingWithEquals{"FOO": &foo, "BAR": &bar, "INHERITED": nil})
+}
@@ -0,0 +1,47 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/compose-spec/compose-go/v2/tree"
+)
+
+// maxFileMode includes permission bits as well as setuid, setgid and sticky bits
+const maxFileMode = 0o7777
+
+// checkFileMode rejects a mode for a mounted config or secret which is not a valid octal file mode
+func checkFileMode(value any, p tree.Path) error {
+	var mode int
+	switch v := value.(type) {
+	case int:
+		mode = v
+	case float64:
+		if v != math.Trunc(v) {
+			return fmt.Errorf("%s: file mode must be an integer, got %v", p, v)
+		}
+		mode = int(v)
+	default:
+		return nil
+	}
+	if mode < 0 || mode > maxFileMode {
+		return fmt.Errorf("%s: file mode %#o is out of range, must be between 0 and %#o", p, mode, maxFileMode)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/tree"
+)
+
+// checkNonNegative rejects negative replicas count, while zero is allowed to scale a service down
+func checkNonNegative(value any, p tree.Path) error {
+	if v, ok := value.(int); ok && v < 0 {
+		return fmt.Errorf("%s: must be a non-negative integer, got %d", p, v)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+/*
+   Copyright 2020 The Compose Specification Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/tree"
+)
+
+// checkUlimit rejects a ulimit set with a soft limit greater than the hard limit
+func checkUlimit(value any, p tree.Path) error {
+	v, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	soft, okSoft := v["soft"].(int)
+	hard, okHard := v["hard"].(int)
+	if okSoft && okHard && soft > hard {
+		return fmt.Errorf("%s: soft limit %d must not exceed hard limit %d", p, soft, hard)
+	}
+	return nil
+}
@@ -26,10 +26,21 @@ import (
 type checkerFunc func(value any, p tree.Path) error
 
 var checks = map[tree.Path]checkerFunc{
-	"volumes.*":                       checkVolume,
-	"configs.*":                       checkFileObject("file", "environment", "content"),
-	"secrets.*":                       checkFileObject("file", "environment"),
-	"services.*.develop.watch.*.path": checkPath,
+	"volumes.*":                                         checkVolume,
+	"configs.*":                                         checkFileObject("file", "environment", "content"),
+	"secrets.*":                                         checkFileObject("file", "environment"),
+	"services.*.build":                                  checkBuild,
+	"services.*.build.secrets.*.mode":                   checkFileMode,
+	"services.*.configs.*.mode":                         checkFileMode,
+	"services.*.secrets.*.mode":                         checkFileMode,
+	"services.*.develop.watch.*.path":                   checkPath,
+	"services.*.restart":                                checkRestart,
+	"services.*.deploy.restart_policy.condition":        checkRestartCondition,
+	"services.*.ulimits.*":                              checkUlimit,
+	"services.*.build.ulimits.*":                        checkUlimit,
+	"services.*.scale":                                  checkNonNegative,
+	"services.*.deploy.replicas":                        checkNonNegative,
+	"services.*.deploy.placement.max_replicas_per_node": checkNonNegative,
 }
 
 func Validate(dict map[string]any) error {
@@ -39,7 +50,11 @@ func Validate(dict map[string]any) error {
 func check(value any, p tree.Path) error {
 	for pattern, fn := range checks {
 		if p.Matches(pattern) {
-			return fn(value, p)
+			if err := fn(value, p); err != nil {
+				return err
+			}
+			// nested attributes may have their own checks
+			break
 		}
 	}
 	switch v := value.(type) {
@@ -94,3 +94,107 @@ external: true
 		})
 	}
 }
+
+func TestValidateBuild(t *testing.T) {
+	checker := checks["services.*.build"]
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name: "with context",
+			input: `
+context: .
+`,
+			err: "",
+		},
+		{
+			name: "blank context",
+			input: `
+context: ""
+dockerfile: Dockerfile
+`,
+			err: `services.foo.build: invalid build, context can't be blank (use "." to build from the project directory)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input map[string]any
+			err := yaml.Unmarshal([]byte(tt.input), &input)
+			assert.NilError(t, err)
+			err = checker(input, tree.NewPath("services.foo.build"))
+			if tt.err == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateNonNegative(t *testing.T) {
+	checker := checks["services.*.deploy.placement.max_replicas_per_node"]
+	p := tree.NewPath("services.foo.deploy.placement.max_replicas_per_node")
+	assert.NilError(t, checker(0, p))
+	assert.NilError(t, checker(2, p))
+	assert.Error(t, checker(-2, p), "services.foo.deploy.placement.max_replicas_per_node: must be a non-negative integer, got -2")
+}
+
+func TestValidateFileMode(t *testing.T) {
+	checker := checks["services.*.secrets.*.mode"]
+	p := tree.NewPath("services.foo.secrets.[].mode")
+	assert.NilError(t, checker(0, p))
+	assert.NilError(t, checker(0o440, p))
+	assert.NilError(t, checker(0o4755, p))
+	assert.NilError(t, checker(float64(0o644), p))
+	assert.Error(t, checker(0o10000, p), "services.foo.secrets.[].mode: file mode 010000 is out of range, must be between 0 and 07777")
+	assert.Error(t, checker(-1, p), "services.foo.secrets.[].mode: file mode -01 is out of range, must be between 0 and 07777")
+	assert.Error(t, checker(4.5, p), "services.foo.secrets.[].mode: file mode must be an integer, got 4.5")
+
+	err := Validate(map[string]any{
+		"services": map[string]any{
+			"foo": map[string]any{
+				"build": map[string]any{
+					"context": ".",
+					"secrets": []any{
+						map[string]any{"source": "token", "mode": 0o20000},
+					},
+				},
+			},
+		},
+	})
+	assert.Error(t, err, "services.foo.build.secrets.[].mode: file mode 020000 is out of range, must be between 0 and 07777")
+}
+
+func TestValidateRestart(t *testing.T) {
+	checker := checks["services.*.restart"]
+	p := tree.NewPath("services.foo.restart")
+	for _, restart := range []string{"", "no", "always", "unless-stopped", "on-failure", "on-failure:3"} {
+		assert.NilError(t, checker(restart, p), restart)
+	}
+	assert.Error(t, checker("allways", p), `services.foo.restart: invalid restart policy "allways", must be one of no, always, unless-stopped or on-failure[:max-retries]`)
+	assert.Error(t, checker("always:3", p), `services.foo.restart: invalid restart policy "always:3", must be one of no, always, unless-stopped or on-failure[:max-retries]`)
+
+	err := Validate(map[string]any{
+		"services": map[string]any{
+			"foo": map[string]any{
+				"deploy": map[string]any{
+					"restart_policy": map[string]any{"condition": ""},
+				},
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	err = Validate(map[string]any{
+		"services": map[string]any{
+			"foo": map[string]any{
+				"deploy": map[string]any{
+					"restart_policy": map[string]any{"condition": "always"},
+				},
+			},
+		},
+	})
+	assert.Error(t, err, `services.foo.deploy.restart_policy.condition: invalid restart_policy condition "always", must be one of none, on-failure or any`)
+}